@@ -54,6 +54,7 @@ func (endpoint *Endpoint) ListRules(ctx context.Context, fields string, filter s
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
@@ -0,0 +1,516 @@
+package goqradar
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time units of the LAST clause.
+const (
+	AQLMinutes = "MINUTES"
+	AQLHours   = "HOURS"
+	AQLDays    = "DAYS"
+)
+
+var aqlIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// AQLExpr is an AQL expression, such as a column, a function call or a literal.
+// Expressions are only created by the functions of this package, so that the
+// values are always escaped. An invalid expression, such as a function with an
+// invalid name, is reported by Build.
+type AQLExpr struct {
+	expr      string
+	alias     string
+	aggregate string
+	err       error
+}
+
+// AQLCondition is an AQL condition used in the WHERE clause.
+type AQLCondition struct {
+	cond string
+	err  error
+}
+
+type aqlOrder struct {
+	expr AQLExpr
+	desc bool
+}
+
+// AQLQuery is an AQL query builder.
+type AQLQuery struct {
+	columns  []AQLExpr
+	database string
+	where    []AQLCondition
+	groupBy  []AQLExpr
+	orderBy  []aqlOrder
+	limit    int
+	start    time.Time
+	stop     time.Time
+	last     int
+	lastUnit string
+}
+
+//------------------------------------------------------------------------------
+// Expressions
+//------------------------------------------------------------------------------
+
+// Col returns a column reference. Names which are not plain identifiers, such
+// as "Log Source", are quoted.
+func Col(name string) AQLExpr {
+	return AQLExpr{expr: quoteIdentifier(name)}
+}
+
+// All returns the * expression.
+func All() AQLExpr {
+	return AQLExpr{expr: "*"}
+}
+
+// Lit returns a literal value. See Literal for the supported types.
+func Lit(value interface{}) AQLExpr {
+	return AQLExpr{expr: Literal(value)}
+}
+
+// Func returns a function call. The name must be a plain identifier.
+func Func(name string, args ...AQLExpr) AQLExpr {
+	var err error
+	if !aqlIdentifierPattern.MatchString(name) {
+		err = fmt.Errorf("invalid function name: %q", name)
+	}
+
+	rendered := make([]string, 0, len(args))
+	for _, arg := range args {
+		rendered = append(rendered, arg.expr)
+		if err == nil {
+			err = arg.err
+		}
+	}
+
+	name = strings.ToUpper(name)
+	expr := AQLExpr{expr: name + "(" + strings.Join(rendered, ", ") + ")", err: err}
+	switch name {
+	case "COUNT", "SUM", "MIN", "MAX", "AVG", "UNIQUECOUNT":
+		expr.aggregate = name
+	}
+
+	return expr
+}
+
+// QIDName returns QIDNAME(expr).
+func QIDName(expr AQLExpr) AQLExpr {
+	return Func("QIDNAME", expr)
+}
+
+// LogSourceName returns LOGSOURCENAME(expr).
+func LogSourceName(expr AQLExpr) AQLExpr {
+	return Func("LOGSOURCENAME", expr)
+}
+
+// CategoryName returns CATEGORYNAME(expr).
+func CategoryName(expr AQLExpr) AQLExpr {
+	return Func("CATEGORYNAME", expr)
+}
+
+// UTF8 returns UTF8(expr).
+func UTF8(expr AQLExpr) AQLExpr {
+	return Func("UTF8", expr)
+}
+
+// Count returns COUNT(expr).
+func Count(expr AQLExpr) AQLExpr {
+	return Func("COUNT", expr)
+}
+
+// Sum returns SUM(expr).
+func Sum(expr AQLExpr) AQLExpr {
+	return Func("SUM", expr)
+}
+
+// As returns the expression with an alias.
+func (e AQLExpr) As(alias string) AQLExpr {
+	e.alias = alias
+	return e
+}
+
+// String returns the rendered expression, with its alias.
+func (e AQLExpr) String() string {
+	if e.alias != "" {
+		return e.expr + " AS " + quoteIdentifier(e.alias)
+	}
+
+	return e.expr
+}
+
+// name returns the name of the column of the expression in the results.
+func (e AQLExpr) name() string {
+	if e.alias != "" {
+		return e.alias
+	}
+
+	return strings.Trim(e.expr, `"`)
+}
+
+//------------------------------------------------------------------------------
+// Conditions
+//------------------------------------------------------------------------------
+
+// Eq returns expr = value.
+func Eq(expr AQLExpr, value interface{}) AQLCondition {
+	return compareExpr(expr, "=", value)
+}
+
+// Neq returns expr != value.
+func Neq(expr AQLExpr, value interface{}) AQLCondition {
+	return compareExpr(expr, "!=", value)
+}
+
+// Gt returns expr > value.
+func Gt(expr AQLExpr, value interface{}) AQLCondition {
+	return compareExpr(expr, ">", value)
+}
+
+// Gte returns expr >= value.
+func Gte(expr AQLExpr, value interface{}) AQLCondition {
+	return compareExpr(expr, ">=", value)
+}
+
+// Lt returns expr < value.
+func Lt(expr AQLExpr, value interface{}) AQLCondition {
+	return compareExpr(expr, "<", value)
+}
+
+// Lte returns expr <= value.
+func Lte(expr AQLExpr, value interface{}) AQLCondition {
+	return compareExpr(expr, "<=", value)
+}
+
+// Like returns expr LIKE pattern.
+func Like(expr AQLExpr, pattern string) AQLCondition {
+	return compareExpr(expr, "LIKE", pattern)
+}
+
+// ILike returns expr ILIKE pattern.
+func ILike(expr AQLExpr, pattern string) AQLCondition {
+	return compareExpr(expr, "ILIKE", pattern)
+}
+
+// In returns expr IN (values...).
+func In(expr AQLExpr, values ...interface{}) AQLCondition {
+	rendered := make([]string, 0, len(values))
+	for _, value := range values {
+		rendered = append(rendered, Literal(value))
+	}
+
+	return AQLCondition{cond: expr.expr + " IN (" + strings.Join(rendered, ", ") + ")", err: expr.err}
+}
+
+// IsNull returns expr IS NULL.
+func IsNull(expr AQLExpr) AQLCondition {
+	return AQLCondition{cond: expr.expr + " IS NULL", err: expr.err}
+}
+
+// IsNotNull returns expr IS NOT NULL.
+func IsNotNull(expr AQLExpr) AQLCondition {
+	return AQLCondition{cond: expr.expr + " IS NOT NULL", err: expr.err}
+}
+
+// InOffense returns INOFFENSE(id).
+func InOffense(id int) AQLCondition {
+	return AQLCondition{cond: "INOFFENSE(" + strconv.Itoa(id) + ")"}
+}
+
+// InCIDR returns INCIDR('cidr', expr).
+func InCIDR(cidr *net.IPNet, expr AQLExpr) AQLCondition {
+	return AQLCondition{cond: "INCIDR(" + Literal(cidr.String()) + ", " + expr.expr + ")", err: expr.err}
+}
+
+// And returns the conjunction of the conditions.
+func And(conds ...AQLCondition) AQLCondition {
+	return joinConditions(conds, " AND ")
+}
+
+// Or returns the disjunction of the conditions.
+func Or(conds ...AQLCondition) AQLCondition {
+	return joinConditions(conds, " OR ")
+}
+
+// Not returns the negation of the condition.
+func Not(cond AQLCondition) AQLCondition {
+	return AQLCondition{cond: "NOT (" + cond.cond + ")", err: cond.err}
+}
+
+// String returns the rendered condition.
+func (c AQLCondition) String() string {
+	return c.cond
+}
+
+func compareExpr(expr AQLExpr, operator string, value interface{}) AQLCondition {
+	return AQLCondition{cond: expr.expr + " " + operator + " " + Literal(value), err: expr.err}
+}
+
+func joinConditions(conds []AQLCondition, separator string) AQLCondition {
+	var err error
+	rendered := make([]string, 0, len(conds))
+	for _, cond := range conds {
+		rendered = append(rendered, "("+cond.cond+")")
+		if err == nil {
+			err = cond.err
+		}
+	}
+
+	return AQLCondition{cond: strings.Join(rendered, separator), err: err}
+}
+
+//------------------------------------------------------------------------------
+// Query
+//------------------------------------------------------------------------------
+
+// Select starts a new query on the events database with the given columns.
+func Select(columns ...AQLExpr) *AQLQuery {
+	return &AQLQuery{
+		columns:  columns,
+		database: DatabaseEvents,
+	}
+}
+
+// From sets the database, either "events" or "flows".
+func (q *AQLQuery) From(database string) *AQLQuery {
+	q.database = database
+	return q
+}
+
+// Where adds conditions, which are joined with AND.
+func (q *AQLQuery) Where(conds ...AQLCondition) *AQLQuery {
+	q.where = append(q.where, conds...)
+	return q
+}
+
+// GroupBy adds GROUP BY expressions.
+func (q *AQLQuery) GroupBy(exprs ...AQLExpr) *AQLQuery {
+	q.groupBy = append(q.groupBy, exprs...)
+	return q
+}
+
+// OrderBy adds an ascending ORDER BY expression.
+func (q *AQLQuery) OrderBy(expr AQLExpr) *AQLQuery {
+	q.orderBy = append(q.orderBy, aqlOrder{expr: expr})
+	return q
+}
+
+// OrderByDesc adds a descending ORDER BY expression.
+func (q *AQLQuery) OrderByDesc(expr AQLExpr) *AQLQuery {
+	q.orderBy = append(q.orderBy, aqlOrder{expr: expr, desc: true})
+	return q
+}
+
+// Limit sets the LIMIT.
+func (q *AQLQuery) Limit(limit int) *AQLQuery {
+	q.limit = limit
+	return q
+}
+
+// Between sets the START and STOP times. The window is half-open: the START
+// time is included and the STOP time is excluded, so that two consecutive
+// windows share their boundary without overlapping.
+func (q *AQLQuery) Between(start, stop time.Time) *AQLQuery {
+	q.start = start
+	q.stop = stop
+	return q
+}
+
+// Last sets the LAST n MINUTES, HOURS or DAYS clause.
+func (q *AQLQuery) Last(n int, unit string) *AQLQuery {
+	q.last = n
+	q.lastUnit = unit
+	return q
+}
+
+// Clone returns a copy of the query.
+func (q *AQLQuery) Clone() *AQLQuery {
+	clone := *q
+	clone.columns = append([]AQLExpr{}, q.columns...)
+	clone.where = append([]AQLCondition{}, q.where...)
+	clone.groupBy = append([]AQLExpr{}, q.groupBy...)
+	clone.orderBy = append([]aqlOrder{}, q.orderBy...)
+
+	return &clone
+}
+
+// Build validates and renders the query.
+func (q *AQLQuery) Build() (string, error) {
+	if len(q.columns) == 0 {
+		return "", fmt.Errorf("the query must select at least one column")
+	}
+	if q.database != DatabaseEvents && q.database != DatabaseFlows {
+		return "", fmt.Errorf("invalid database: %s", q.database)
+	}
+	if q.last > 0 && (!q.start.IsZero() || !q.stop.IsZero()) {
+		return "", fmt.Errorf("LAST and START/STOP are mutually exclusive")
+	}
+	if q.start.IsZero() != q.stop.IsZero() {
+		return "", fmt.Errorf("START and STOP must be set together")
+	}
+	if !q.start.IsZero() && !q.stop.After(q.start) {
+		return "", fmt.Errorf("STOP must be after START")
+	}
+	if q.last > 0 && q.lastUnit != AQLMinutes && q.lastUnit != AQLHours && q.lastUnit != AQLDays {
+		return "", fmt.Errorf("invalid LAST unit: %s", q.lastUnit)
+	}
+	if err := q.exprError(); err != nil {
+		return "", err
+	}
+
+	return q.render(), nil
+}
+
+// exprError returns the first error of the expressions and the conditions.
+func (q *AQLQuery) exprError() error {
+	exprs := append(append([]AQLExpr{}, q.columns...), q.groupBy...)
+	for _, order := range q.orderBy {
+		exprs = append(exprs, order.expr)
+	}
+	for _, expr := range exprs {
+		if expr.err != nil {
+			return expr.err
+		}
+	}
+	for _, cond := range q.where {
+		if cond.err != nil {
+			return cond.err
+		}
+	}
+
+	return nil
+}
+
+// String renders the query without validating it.
+func (q *AQLQuery) String() string {
+	return q.render()
+}
+
+func (q *AQLQuery) render() string {
+	var b strings.Builder
+
+	// SELECT
+	columns := make([]string, 0, len(q.columns))
+	for _, column := range q.columns {
+		columns = append(columns, column.String())
+	}
+	b.WriteString("SELECT " + strings.Join(columns, ", "))
+
+	// FROM
+	b.WriteString(" FROM " + q.database)
+
+	// WHERE
+	if len(q.where) > 0 {
+		if len(q.where) == 1 {
+			b.WriteString(" WHERE " + q.where[0].cond)
+		} else {
+			b.WriteString(" WHERE " + And(q.where...).cond)
+		}
+	}
+
+	// GROUP BY
+	if len(q.groupBy) > 0 {
+		groupBy := make([]string, 0, len(q.groupBy))
+		for _, expr := range q.groupBy {
+			groupBy = append(groupBy, expr.expr)
+		}
+		b.WriteString(" GROUP BY " + strings.Join(groupBy, ", "))
+	}
+
+	// ORDER BY
+	if len(q.orderBy) > 0 {
+		orderBy := make([]string, 0, len(q.orderBy))
+		for _, order := range q.orderBy {
+			if order.desc {
+				orderBy = append(orderBy, order.expr.expr+" DESC")
+			} else {
+				orderBy = append(orderBy, order.expr.expr+" ASC")
+			}
+		}
+		b.WriteString(" ORDER BY " + strings.Join(orderBy, ", "))
+	}
+
+	// LIMIT
+	if q.limit > 0 {
+		b.WriteString(" LIMIT " + strconv.Itoa(q.limit))
+	}
+
+	// Time clause
+	if !q.start.IsZero() {
+		b.WriteString(" START " + strconv.FormatInt(epochMillis(q.start), 10))
+		b.WriteString(" STOP " + strconv.FormatInt(epochMillis(q.stop), 10))
+	} else if q.last > 0 {
+		b.WriteString(" LAST " + strconv.Itoa(q.last) + " " + q.lastUnit)
+	}
+
+	return b.String()
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// Literal renders a value as an AQL literal. Strings are single quoted with
+// the quotes escaped, IPs and networks are rendered as strings, and times as
+// epoch milliseconds.
+func Literal(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case AQLExpr:
+		return v.expr
+	case string:
+		return quoteString(v)
+	case net.IP:
+		return quoteString(v.String())
+	case *net.IPNet:
+		return quoteString(v.String())
+	case time.Time:
+		return strconv.FormatInt(epochMillis(v), 10)
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case int:
+		return strconv.Itoa(v)
+	case int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case fmt.Stringer:
+		return quoteString(v.String())
+	}
+
+	return quoteString(fmt.Sprintf("%v", value))
+}
+
+// quoteString single quotes a string. Both the backslashes and the quotes are
+// doubled, so that the value can never terminate the literal.
+func quoteString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `''`, -1)
+
+	return "'" + s + "'"
+}
+
+// quoteIdentifier double quotes an identifier when it is not a plain one.
+func quoteIdentifier(name string) string {
+	if aqlIdentifierPattern.MatchString(name) {
+		return name
+	}
+
+	return `"` + strings.Replace(name, `"`, `\"`, -1) + `"`
+}
+
+func epochMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
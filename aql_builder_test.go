@@ -0,0 +1,65 @@
+package goqradar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAQLQueryBuild(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	query, err := Select(
+		Col("sourceip"),
+		QIDName(Col("qid")).As("Event Name"),
+		Count(All()).As("total"),
+	).
+		From(DatabaseEvents).
+		Where(Eq(Col("username"), "o'neil"), InOffense(42)).
+		GroupBy(Col("sourceip"), Col("qid")).
+		OrderByDesc(Col("total")).
+		Limit(10).
+		Between(start, start.Add(time.Hour)).
+		Build()
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	expected := `SELECT sourceip, QIDNAME(qid) AS "Event Name", COUNT(*) AS total FROM events WHERE (username = 'o''neil') AND (INOFFENSE(42)) GROUP BY sourceip, qid ORDER BY total DESC LIMIT 10 START 1600000000000 STOP 1600003600000`
+	if query != expected {
+		t.Fatalf("unexpected query:\n%s\n%s", query, expected)
+	}
+}
+
+func TestAQLQueryBuildErrors(t *testing.T) {
+	if _, err := Select().Build(); err == nil {
+		t.Fatal("should error without columns")
+	}
+
+	now := time.Now()
+	if _, err := Select(All()).Between(now, now.Add(time.Hour)).Last(5, AQLMinutes).Build(); err == nil {
+		t.Fatal("should error with LAST and START/STOP")
+	}
+
+	if _, err := Select(All()).From("assets").Build(); err == nil {
+		t.Fatal("should error with an invalid database")
+	}
+
+	// The function names are not escaped, they are validated
+	if _, err := Select(Func("X) OR 1=1 --", Col("qid"))).Build(); err == nil {
+		t.Fatal("should error with an invalid function name")
+	}
+	if _, err := Select(All()).Where(Not(Eq(Count(Func("a b")), 1))).Build(); err == nil {
+		t.Fatal("should error with an invalid function name in a condition")
+	}
+	if _, err := Select(Func("qidname", Col("qid"))).Build(); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	if got := Literal(`a\' OR 1=1`); got != `'a\\'' OR 1=1'` {
+		t.Fatalf("unexpected literal: %s", got)
+	}
+	if got := Literal(5); got != "5" {
+		t.Fatalf("unexpected literal: %s", got)
+	}
+}
@@ -0,0 +1,212 @@
+package goqradar
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type aqlTokenKind int
+
+const (
+	aqlTokenEOF aqlTokenKind = iota
+	aqlTokenIdent
+	aqlTokenQuotedIdent
+	aqlTokenString
+	aqlTokenNumber
+	aqlTokenOperator
+	aqlTokenComma
+	aqlTokenLParen
+	aqlTokenRParen
+)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// AQLPosition is a position in an AQL query. Lines and columns start at 1.
+type AQLPosition struct {
+	Line   int
+	Column int
+}
+
+// AQLError is a syntax or validation error of an AQL query.
+type AQLError struct {
+	AQLPosition
+	Message string
+}
+
+// Error returns the message prefixed by the position.
+func (e *AQLError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+type aqlToken struct {
+	kind  aqlTokenKind
+	value string
+	pos   AQLPosition
+}
+
+// is returns true if the token is the given keyword or operator.
+func (t aqlToken) is(value string) bool {
+	switch t.kind {
+	case aqlTokenIdent:
+		return strings.EqualFold(t.value, value)
+	case aqlTokenOperator:
+		return t.value == value
+	}
+
+	return false
+}
+
+func (t aqlToken) String() string {
+	switch t.kind {
+	case aqlTokenEOF:
+		return "end of query"
+	case aqlTokenString:
+		return "'" + t.value + "'"
+	case aqlTokenQuotedIdent:
+		return `"` + t.value + `"`
+	}
+
+	return t.value
+}
+
+type aqlLexer struct {
+	src  []rune
+	i    int
+	line int
+	col  int
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// lexAQL splits the query into tokens.
+func lexAQL(query string) ([]aqlToken, error) {
+	l := &aqlLexer{src: []rune(query), line: 1, col: 1}
+
+	tokens := []aqlToken{}
+	for {
+		token, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+
+		if token.kind == aqlTokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *aqlLexer) peek(offset int) rune {
+	if l.i+offset >= len(l.src) {
+		return 0
+	}
+
+	return l.src[l.i+offset]
+}
+
+func (l *aqlLexer) advance() rune {
+	r := l.src[l.i]
+	l.i++
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+
+	return r
+}
+
+func (l *aqlLexer) errorf(pos AQLPosition, format string, args ...interface{}) error {
+	return &AQLError{AQLPosition: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (l *aqlLexer) next() (aqlToken, error) {
+	// Skip the whitespaces
+	for l.i < len(l.src) && unicode.IsSpace(l.peek(0)) {
+		l.advance()
+	}
+
+	pos := AQLPosition{Line: l.line, Column: l.col}
+	if l.i >= len(l.src) {
+		return aqlToken{kind: aqlTokenEOF, pos: pos}, nil
+	}
+
+	r := l.peek(0)
+	switch {
+	case r == '\'':
+		value, err := l.quoted('\'', pos)
+		return aqlToken{kind: aqlTokenString, value: value, pos: pos}, err
+	case r == '"':
+		value, err := l.quoted('"', pos)
+		return aqlToken{kind: aqlTokenQuotedIdent, value: value, pos: pos}, err
+	case unicode.IsDigit(r) || (r == '.' && unicode.IsDigit(l.peek(1))):
+		var b strings.Builder
+		dot := false
+		for l.i < len(l.src) && (unicode.IsDigit(l.peek(0)) || (l.peek(0) == '.' && !dot)) {
+			if l.peek(0) == '.' {
+				dot = true
+			}
+			b.WriteRune(l.advance())
+		}
+		return aqlToken{kind: aqlTokenNumber, value: b.String(), pos: pos}, nil
+	case unicode.IsLetter(r) || r == '_':
+		var b strings.Builder
+		for l.i < len(l.src) && (unicode.IsLetter(l.peek(0)) || unicode.IsDigit(l.peek(0)) || l.peek(0) == '_') {
+			b.WriteRune(l.advance())
+		}
+		return aqlToken{kind: aqlTokenIdent, value: b.String(), pos: pos}, nil
+	case r == ',':
+		l.advance()
+		return aqlToken{kind: aqlTokenComma, value: ",", pos: pos}, nil
+	case r == '(':
+		l.advance()
+		return aqlToken{kind: aqlTokenLParen, value: "(", pos: pos}, nil
+	case r == ')':
+		l.advance()
+		return aqlToken{kind: aqlTokenRParen, value: ")", pos: pos}, nil
+	}
+
+	// Operators
+	two := string([]rune{r, l.peek(1)})
+	switch two {
+	case "!=", "<>", "<=", ">=", "||":
+		l.advance()
+		l.advance()
+		return aqlToken{kind: aqlTokenOperator, value: two, pos: pos}, nil
+	}
+	if strings.ContainsRune("=<>+-*/%&|^", r) {
+		l.advance()
+		return aqlToken{kind: aqlTokenOperator, value: string(r), pos: pos}, nil
+	}
+
+	return aqlToken{}, l.errorf(pos, "unexpected character %q", r)
+}
+
+// quoted reads a literal delimited by the quote. The quote is escaped either
+// by doubling it or with a backslash.
+func (l *aqlLexer) quoted(quote rune, pos AQLPosition) (string, error) {
+	l.advance()
+
+	var b strings.Builder
+	for l.i < len(l.src) {
+		r := l.advance()
+		switch {
+		case r == '\\' && l.i < len(l.src):
+			b.WriteRune(l.advance())
+		case r == quote && l.peek(0) == quote:
+			b.WriteRune(l.advance())
+		case r == quote:
+			return b.String(), nil
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return "", l.errorf(pos, "unterminated literal")
+}
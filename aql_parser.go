@@ -0,0 +1,862 @@
+package goqradar
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats accepted by the START and STOP clauses.
+var aqlTimeFormats = []string{"2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// Keywords which cannot be used as column names.
+var aqlReservedWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "BY": true,
+	"HAVING": true, "ORDER": true, "LIMIT": true, "LAST": true, "START": true,
+	"STOP": true, "AND": true, "OR": true, "NOT": true, "AS": true, "ASC": true,
+	"DESC": true, "IN": true, "IS": true, "LIKE": true, "ILIKE": true,
+	"MATCHES": true, "IMATCHES": true, "BETWEEN": true,
+}
+
+//------------------------------------------------------------------------------
+// AST
+//------------------------------------------------------------------------------
+
+// AQLNode is a node of an AQL expression.
+type AQLNode interface {
+	Position() AQLPosition
+}
+
+// AQLStar is the * of SELECT * or COUNT(*).
+type AQLStar struct {
+	Pos AQLPosition
+}
+
+// AQLColumnRef is a reference to a column or to an alias.
+type AQLColumnRef struct {
+	Pos    AQLPosition
+	Name   string
+	Quoted bool
+}
+
+// AQLLiteral is a string, number, boolean or NULL literal.
+type AQLLiteral struct {
+	Pos   AQLPosition
+	Kind  string
+	Value string
+}
+
+// AQLFuncCall is a function call.
+type AQLFuncCall struct {
+	Pos  AQLPosition
+	Name string
+	Args []AQLNode
+}
+
+// AQLBinary is a binary expression, such as a comparison or a logical operator.
+type AQLBinary struct {
+	Pos   AQLPosition
+	Op    string
+	Left  AQLNode
+	Right AQLNode
+}
+
+// AQLUnary is a NOT or a negation.
+type AQLUnary struct {
+	Pos AQLPosition
+	Op  string
+	X   AQLNode
+}
+
+// AQLIn is expr [NOT] IN (values).
+type AQLIn struct {
+	Pos    AQLPosition
+	X      AQLNode
+	Values []AQLNode
+	Not    bool
+}
+
+// AQLIsNull is expr IS [NOT] NULL.
+type AQLIsNull struct {
+	Pos AQLPosition
+	X   AQLNode
+	Not bool
+}
+
+// AQLBetween is expr [NOT] BETWEEN low AND high.
+type AQLBetween struct {
+	Pos  AQLPosition
+	X    AQLNode
+	Low  AQLNode
+	High AQLNode
+	Not  bool
+}
+
+// Position returns the position of the node.
+func (n *AQLStar) Position() AQLPosition { return n.Pos }
+
+// Position returns the position of the node.
+func (n *AQLColumnRef) Position() AQLPosition { return n.Pos }
+
+// Position returns the position of the node.
+func (n *AQLLiteral) Position() AQLPosition { return n.Pos }
+
+// Position returns the position of the node.
+func (n *AQLFuncCall) Position() AQLPosition { return n.Pos }
+
+// Position returns the position of the node.
+func (n *AQLBinary) Position() AQLPosition { return n.Pos }
+
+// Position returns the position of the node.
+func (n *AQLUnary) Position() AQLPosition { return n.Pos }
+
+// Position returns the position of the node.
+func (n *AQLIn) Position() AQLPosition { return n.Pos }
+
+// Position returns the position of the node.
+func (n *AQLIsNull) Position() AQLPosition { return n.Pos }
+
+// Position returns the position of the node.
+func (n *AQLBetween) Position() AQLPosition { return n.Pos }
+
+// AQLSelectItem is an item of the SELECT clause.
+type AQLSelectItem struct {
+	Expr  AQLNode
+	Alias string
+}
+
+// AQLOrderItem is an item of the ORDER BY clause.
+type AQLOrderItem struct {
+	Expr AQLNode
+	Desc bool
+}
+
+// AQLTimeClause is either LAST n UNIT or START ... STOP ....
+type AQLTimeClause struct {
+	Pos   AQLPosition
+	Last  int
+	Unit  string
+	Start time.Time
+	Stop  time.Time
+}
+
+// AQLStatement is a parsed AQL query.
+type AQLStatement struct {
+	Select   []*AQLSelectItem
+	From     string
+	FromPos  AQLPosition
+	Where    AQLNode
+	GroupBy  []AQLNode
+	Having   AQLNode
+	OrderBy  []*AQLOrderItem
+	Limit    int
+	Time     *AQLTimeClause
+	Database string
+}
+
+type aqlParser struct {
+	tokens []aqlToken
+	i      int
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// ParseAQL parses the query and validates its syntax and its time clause.
+// The returned error is an *AQLError carrying the position of the problem.
+func ParseAQL(query string) (*AQLStatement, error) {
+	tokens, err := lexAQL(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &aqlParser{tokens: tokens}
+
+	return p.statement()
+}
+
+// ValidateAQL parses the query and, when a schema of its database is given,
+// checks the column references. It returns all the errors found.
+func ValidateAQL(query string, schemas map[string]*Database) []*AQLError {
+	stmt, err := ParseAQL(query)
+	if err != nil {
+		return []*AQLError{err.(*AQLError)}
+	}
+
+	schema, ok := schemas[stmt.Database]
+	if !ok {
+		return nil
+	}
+
+	return stmt.CheckSchema(schema)
+}
+
+// CheckSchema checks the column references against the database schema.
+// The aliases defined in the SELECT clause can be referenced.
+func (s *AQLStatement) CheckSchema(schema *Database) []*AQLError {
+	aliases := map[string]bool{}
+	for _, item := range s.Select {
+		if item.Alias != "" {
+			aliases[strings.ToLower(item.Alias)] = true
+		}
+	}
+
+	errs := []*AQLError{}
+	check := func(node AQLNode, allowAliases bool) {
+		walkAQL(node, func(n AQLNode) {
+			ref, ok := n.(*AQLColumnRef)
+			if !ok || schema.HasColumn(ref.Name) {
+				return
+			}
+			if allowAliases && aliases[strings.ToLower(ref.Name)] {
+				return
+			}
+			errs = append(errs, &AQLError{AQLPosition: ref.Pos, Message: fmt.Sprintf("unknown column %q in %s", ref.Name, schema.Name)})
+		})
+	}
+
+	for _, item := range s.Select {
+		check(item.Expr, false)
+	}
+	check(s.Where, false)
+	for _, node := range s.GroupBy {
+		check(node, true)
+	}
+	check(s.Having, true)
+	for _, item := range s.OrderBy {
+		check(item.Expr, true)
+	}
+	sortAQLErrors(errs)
+
+	return errs
+}
+
+// WriteSchemaSnapshot writes the schemas, as returned by ListDatabaseSchemas,
+// into a JSON file which can be used offline.
+func WriteSchemaSnapshot(path string, schemas map[string]*Database) error {
+	b, err := json.MarshalIndent(schemas, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error while marshalling the schemas: %s", err)
+	}
+
+	err = ioutil.WriteFile(path, b, 0644)
+	if err != nil {
+		return fmt.Errorf("error while writing the schemas: %s", err)
+	}
+
+	return nil
+}
+
+// ReadSchemaSnapshot reads the schemas written by WriteSchemaSnapshot.
+func ReadSchemaSnapshot(path string) (map[string]*Database, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading the schemas: %s", err)
+	}
+
+	schemas := map[string]*Database{}
+	err = json.Unmarshal(b, &schemas)
+	if err != nil {
+		return nil, fmt.Errorf("error while unmarshalling the schemas: %s", err)
+	}
+
+	for name, schema := range schemas {
+		schema.Name = name
+	}
+
+	return schemas, nil
+}
+
+//------------------------------------------------------------------------------
+// Parser
+//------------------------------------------------------------------------------
+
+func (p *aqlParser) peek() aqlToken {
+	return p.tokens[p.i]
+}
+
+func (p *aqlParser) next() aqlToken {
+	t := p.tokens[p.i]
+	if t.kind != aqlTokenEOF {
+		p.i++
+	}
+
+	return t
+}
+
+func (p *aqlParser) accept(value string) bool {
+	if p.peek().is(value) {
+		p.next()
+		return true
+	}
+
+	return false
+}
+
+func (p *aqlParser) expect(value string) (aqlToken, error) {
+	t := p.peek()
+	if !t.is(value) {
+		return t, p.errorf(t, "expected %s, found %s", value, t)
+	}
+
+	return p.next(), nil
+}
+
+func (p *aqlParser) errorf(t aqlToken, format string, args ...interface{}) *AQLError {
+	return &AQLError{AQLPosition: t.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *aqlParser) statement() (*AQLStatement, error) {
+	stmt := &AQLStatement{}
+
+	// SELECT
+	if _, err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	for {
+		item, err := p.selectItem()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Select = append(stmt.Select, item)
+
+		if p.peek().kind != aqlTokenComma {
+			break
+		}
+		p.next()
+	}
+
+	// FROM
+	if _, err := p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	from := p.next()
+	if from.kind != aqlTokenIdent {
+		return nil, p.errorf(from, "expected a database, found %s", from)
+	}
+	stmt.From = from.value
+	stmt.FromPos = from.pos
+	stmt.Database = strings.ToLower(from.value)
+	if stmt.Database != DatabaseEvents && stmt.Database != DatabaseFlows {
+		return nil, p.errorf(from, "unknown database %s, expected events or flows", from.value)
+	}
+
+	// WHERE
+	if p.accept("WHERE") {
+		where, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Where = where
+	}
+
+	// GROUP BY
+	if p.accept("GROUP") {
+		if _, err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		list, err := p.exprList()
+		if err != nil {
+			return nil, err
+		}
+		stmt.GroupBy = list
+	}
+
+	// HAVING
+	if p.accept("HAVING") {
+		having, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Having = having
+	}
+
+	// ORDER BY
+	if p.accept("ORDER") {
+		if _, err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			expr, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			item := &AQLOrderItem{Expr: expr}
+			if p.accept("DESC") {
+				item.Desc = true
+			} else {
+				p.accept("ASC")
+			}
+			stmt.OrderBy = append(stmt.OrderBy, item)
+
+			if p.peek().kind != aqlTokenComma {
+				break
+			}
+			p.next()
+		}
+	}
+
+	// LIMIT and time clause, in any order
+	for {
+		t := p.peek()
+		switch {
+		case t.is("LIMIT"):
+			if stmt.Limit > 0 {
+				return nil, p.errorf(t, "duplicate LIMIT clause")
+			}
+			p.next()
+			n, err := p.positiveInt()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Limit = n
+		case t.is("LAST") || t.is("START"):
+			if stmt.Time != nil {
+				return nil, p.errorf(t, "duplicate time clause")
+			}
+			clause, err := p.timeClause()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Time = clause
+		case t.is("STOP"):
+			return nil, p.errorf(t, "STOP without START")
+		case t.kind == aqlTokenEOF:
+			return stmt, nil
+		default:
+			return nil, p.errorf(t, "unexpected %s", t)
+		}
+	}
+}
+
+func (p *aqlParser) selectItem() (*AQLSelectItem, error) {
+	if p.peek().is("*") {
+		t := p.next()
+		return &AQLSelectItem{Expr: &AQLStar{Pos: t.pos}}, nil
+	}
+
+	expr, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	item := &AQLSelectItem{Expr: expr}
+
+	if p.accept("AS") {
+		alias := p.next()
+		if alias.kind != aqlTokenIdent && alias.kind != aqlTokenQuotedIdent && alias.kind != aqlTokenString {
+			return nil, p.errorf(alias, "expected an alias, found %s", alias)
+		}
+		item.Alias = alias.value
+	}
+
+	return item, nil
+}
+
+func (p *aqlParser) exprList() ([]AQLNode, error) {
+	list := []AQLNode{}
+	for {
+		expr, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, expr)
+
+		if p.peek().kind != aqlTokenComma {
+			return list, nil
+		}
+		p.next()
+	}
+}
+
+func (p *aqlParser) positiveInt() (int, error) {
+	t := p.next()
+	if t.kind != aqlTokenNumber {
+		return 0, p.errorf(t, "expected a number, found %s", t)
+	}
+
+	n, err := strconv.Atoi(t.value)
+	if err != nil || n <= 0 {
+		return 0, p.errorf(t, "expected a positive integer, found %s", t)
+	}
+
+	return n, nil
+}
+
+func (p *aqlParser) timeClause() (*AQLTimeClause, error) {
+	t := p.next()
+	clause := &AQLTimeClause{Pos: t.pos}
+
+	if t.is("LAST") {
+		n, err := p.positiveInt()
+		if err != nil {
+			return nil, err
+		}
+		unit := p.next()
+		switch strings.ToUpper(unit.value) {
+		case "MINUTE", "MINUTES":
+			clause.Unit = AQLMinutes
+		case "HOUR", "HOURS":
+			clause.Unit = AQLHours
+		case "DAY", "DAYS":
+			clause.Unit = AQLDays
+		default:
+			return nil, p.errorf(unit, "expected MINUTES, HOURS or DAYS, found %s", unit)
+		}
+		clause.Last = n
+
+		return clause, nil
+	}
+
+	// START ... STOP ...
+	start, err := p.timeValue()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect("STOP"); err != nil {
+		return nil, err
+	}
+	stopToken := p.peek()
+	stop, err := p.timeValue()
+	if err != nil {
+		return nil, err
+	}
+	if !stop.After(start) {
+		return nil, p.errorf(stopToken, "STOP must be after START")
+	}
+	clause.Start = start
+	clause.Stop = stop
+
+	return clause, nil
+}
+
+func (p *aqlParser) timeValue() (time.Time, error) {
+	t := p.next()
+
+	switch t.kind {
+	case aqlTokenNumber:
+		ms, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			return time.Time{}, p.errorf(t, "invalid epoch time %s", t.value)
+		}
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	case aqlTokenString:
+		for _, format := range aqlTimeFormats {
+			if v, err := time.Parse(format, t.value); err == nil {
+				return v, nil
+			}
+		}
+		return time.Time{}, p.errorf(t, "invalid time %s, expected yyyy-MM-dd HH:mm[:ss]", t)
+	}
+
+	return time.Time{}, p.errorf(t, "expected a time, found %s", t)
+}
+
+// expr parses an expression, with the lowest precedence first.
+func (p *aqlParser) expr() (AQLNode, error) {
+	return p.or()
+}
+
+func (p *aqlParser) or() (AQLNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().is("OR") {
+		t := p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &AQLBinary{Pos: t.pos, Op: "OR", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *aqlParser) and() (AQLNode, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().is("AND") {
+		t := p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &AQLBinary{Pos: t.pos, Op: "AND", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *aqlParser) not() (AQLNode, error) {
+	if p.peek().is("NOT") {
+		t := p.next()
+		x, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &AQLUnary{Pos: t.pos, Op: "NOT", X: x}, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *aqlParser) comparison() (AQLNode, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	switch {
+	case t.kind == aqlTokenOperator && (t.value == "=" || t.value == "!=" || t.value == "<>" || t.value == "<" || t.value == "<=" || t.value == ">" || t.value == ">="):
+		p.next()
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		return &AQLBinary{Pos: t.pos, Op: t.value, Left: left, Right: right}, nil
+	case t.is("IS"):
+		p.next()
+		not := p.accept("NOT")
+		if _, err := p.expect("NULL"); err != nil {
+			return nil, err
+		}
+		return &AQLIsNull{Pos: t.pos, X: left, Not: not}, nil
+	}
+
+	not := false
+	if t.is("NOT") {
+		p.next()
+		not = true
+		t = p.peek()
+	}
+
+	switch {
+	case t.is("LIKE") || t.is("ILIKE") || t.is("MATCHES") || t.is("IMATCHES"):
+		p.next()
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		var node AQLNode = &AQLBinary{Pos: t.pos, Op: strings.ToUpper(t.value), Left: left, Right: right}
+		if not {
+			node = &AQLUnary{Pos: t.pos, Op: "NOT", X: node}
+		}
+		return node, nil
+	case t.is("IN"):
+		p.next()
+		if p.peek().kind != aqlTokenLParen {
+			return nil, p.errorf(p.peek(), "expected (, found %s", p.peek())
+		}
+		p.next()
+		values, err := p.exprList()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != aqlTokenRParen {
+			return nil, p.errorf(p.peek(), "expected ), found %s", p.peek())
+		}
+		p.next()
+		return &AQLIn{Pos: t.pos, X: left, Values: values, Not: not}, nil
+	case t.is("BETWEEN"):
+		p.next()
+		low, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		return &AQLBetween{Pos: t.pos, X: left, Low: low, High: high, Not: not}, nil
+	}
+
+	if not {
+		return nil, p.errorf(t, "expected LIKE, ILIKE, MATCHES, IMATCHES, IN or BETWEEN after NOT, found %s", t)
+	}
+
+	return left, nil
+}
+
+func (p *aqlParser) additive() (AQLNode, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if !(t.is("+") || t.is("-") || t.is("||") || t.is("&") || t.is("|") || t.is("^")) {
+			return left, nil
+		}
+		p.next()
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &AQLBinary{Pos: t.pos, Op: t.value, Left: left, Right: right}
+	}
+}
+
+func (p *aqlParser) multiplicative() (AQLNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if !(t.is("*") || t.is("/") || t.is("%")) {
+			return left, nil
+		}
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &AQLBinary{Pos: t.pos, Op: t.value, Left: left, Right: right}
+	}
+}
+
+func (p *aqlParser) unary() (AQLNode, error) {
+	if p.peek().is("-") {
+		t := p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &AQLUnary{Pos: t.pos, Op: "-", X: x}, nil
+	}
+
+	return p.primary()
+}
+
+func (p *aqlParser) primary() (AQLNode, error) {
+	t := p.next()
+
+	switch t.kind {
+	case aqlTokenNumber:
+		return &AQLLiteral{Pos: t.pos, Kind: "number", Value: t.value}, nil
+	case aqlTokenString:
+		return &AQLLiteral{Pos: t.pos, Kind: "string", Value: t.value}, nil
+	case aqlTokenQuotedIdent:
+		return &AQLColumnRef{Pos: t.pos, Name: t.value, Quoted: true}, nil
+	case aqlTokenLParen:
+		expr, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != aqlTokenRParen {
+			return nil, p.errorf(p.peek(), "expected ), found %s", p.peek())
+		}
+		p.next()
+		return expr, nil
+	case aqlTokenIdent:
+		upper := strings.ToUpper(t.value)
+		switch upper {
+		case "TRUE", "FALSE":
+			return &AQLLiteral{Pos: t.pos, Kind: "boolean", Value: upper}, nil
+		case "NULL":
+			return &AQLLiteral{Pos: t.pos, Kind: "null", Value: upper}, nil
+		}
+
+		// Function call
+		if p.peek().kind == aqlTokenLParen {
+			p.next()
+			call := &AQLFuncCall{Pos: t.pos, Name: upper}
+			if p.peek().kind == aqlTokenRParen {
+				p.next()
+				return call, nil
+			}
+			for {
+				var arg AQLNode
+				if p.peek().is("*") {
+					star := p.next()
+					arg = &AQLStar{Pos: star.pos}
+				} else {
+					var err error
+					p.accept("DISTINCT")
+					arg, err = p.expr()
+					if err != nil {
+						return nil, err
+					}
+				}
+				call.Args = append(call.Args, arg)
+
+				next := p.next()
+				if next.kind == aqlTokenRParen {
+					return call, nil
+				}
+				if next.kind != aqlTokenComma {
+					return nil, p.errorf(next, "expected , or ), found %s", next)
+				}
+			}
+		}
+
+		if aqlReservedWords[upper] {
+			return nil, p.errorf(t, "unexpected keyword %s", t.value)
+		}
+
+		return &AQLColumnRef{Pos: t.pos, Name: t.value}, nil
+	}
+
+	return nil, p.errorf(t, "unexpected %s", t)
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// walkAQL calls fn for the node and all its descendants.
+func walkAQL(node AQLNode, fn func(AQLNode)) {
+	if node == nil {
+		return
+	}
+	fn(node)
+
+	switch n := node.(type) {
+	case *AQLFuncCall:
+		for _, arg := range n.Args {
+			walkAQL(arg, fn)
+		}
+	case *AQLBinary:
+		walkAQL(n.Left, fn)
+		walkAQL(n.Right, fn)
+	case *AQLUnary:
+		walkAQL(n.X, fn)
+	case *AQLIn:
+		walkAQL(n.X, fn)
+		for _, value := range n.Values {
+			walkAQL(value, fn)
+		}
+	case *AQLIsNull:
+		walkAQL(n.X, fn)
+	case *AQLBetween:
+		walkAQL(n.X, fn)
+		walkAQL(n.Low, fn)
+		walkAQL(n.High, fn)
+	}
+}
+
+// sortAQLErrors sorts the errors by position.
+func sortAQLErrors(errs []*AQLError) {
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+}
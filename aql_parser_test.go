@@ -0,0 +1,88 @@
+package goqradar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAQL(t *testing.T) {
+	query := `SELECT sourceip, QIDNAME(qid) AS "Event Name", COUNT(*) AS total
+FROM events
+WHERE username = 'o''neil' AND (magnitude >= 5 OR sourceip NOT IN ('10.0.0.1', '10.0.0.2')) AND INOFFENSE(42)
+GROUP BY sourceip, qid
+ORDER BY total DESC
+LIMIT 10
+START '2020-09-13 12:00' STOP '2020-09-13 13:00'`
+
+	stmt, err := ParseAQL(query)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	if len(stmt.Select) != 3 || stmt.Select[1].Alias != "Event Name" {
+		t.Fatalf("unexpected select: %+v", stmt.Select)
+	}
+	if stmt.Database != DatabaseEvents || stmt.Limit != 10 || len(stmt.GroupBy) != 2 {
+		t.Fatalf("unexpected statement: %+v", stmt)
+	}
+	if stmt.Time.Stop.Sub(stmt.Time.Start) != time.Hour {
+		t.Fatalf("unexpected time clause: %+v", stmt.Time)
+	}
+}
+
+func TestParseAQLBuilderOutput(t *testing.T) {
+	query, err := Select(Col("sourceip"), Count(All()).As("total")).
+		Where(Eq(Col("username"), `a\'b`), IsNotNull(Col("username"))).
+		GroupBy(Col("sourceip")).
+		Last(2, AQLHours).
+		Build()
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	stmt, err := ParseAQL(query)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	eq := stmt.Where.(*AQLBinary).Left.(*AQLBinary)
+	if eq.Right.(*AQLLiteral).Value != `a\'b` {
+		t.Fatalf("unexpected literal: %s", eq.Right.(*AQLLiteral).Value)
+	}
+}
+
+func TestParseAQLErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		line   int
+		column int
+	}{
+		{"SELECT * FROM assets", 1, 15},
+		{"SELECT *\nFROM events\nWHERE sourceip = ", 3, 18},
+		{"SELECT * FROM events LAST 5 WEEKS", 1, 29},
+		{"SELECT * FROM events START '2020-09-13 13:00' STOP '2020-09-13 12:00'", 1, 52},
+		{"SELECT * FROM events WHERE username = 'abc", 1, 39},
+	}
+
+	for _, test := range tests {
+		_, err := ParseAQL(test.query)
+		aqlErr, ok := err.(*AQLError)
+		if !ok {
+			t.Fatalf("%q should return an AQL error, got: %v", test.query, err)
+		}
+		if aqlErr.Line != test.line || aqlErr.Column != test.column {
+			t.Fatalf("%q: unexpected position %d:%d, expected %d:%d", test.query, aqlErr.Line, aqlErr.Column, test.line, test.column)
+		}
+	}
+}
+
+func TestValidateAQLSchema(t *testing.T) {
+	schemas := map[string]*Database{
+		DatabaseEvents: {Name: DatabaseEvents, Columns: []Columns{{Name: "sourceip"}, {Name: "qid"}}},
+	}
+
+	errs := ValidateAQL("SELECT sourceip, COUNT(*) AS total FROM events WHERE destinationip = '1.1.1.1' GROUP BY sourceip ORDER BY total", schemas)
+	if len(errs) != 1 || errs[0].Column != 54 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
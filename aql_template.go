@@ -0,0 +1,328 @@
+package goqradar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of the template parameters.
+const (
+	ParamString    = "string"
+	ParamInt       = "int"
+	ParamIP        = "ip"
+	ParamCIDR      = "cidr"
+	ParamTime      = "time"
+	ParamDuration  = "duration"
+	ParamTimeRange = "timerange"
+)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// AQLTemplate is an AQL query with named parameters, written ${name}. The
+// parameters are typed, and are rendered as escaped literals:
+//
+//   - string, ip and cidr are rendered as quoted strings
+//   - int is rendered as a number
+//   - time is rendered as epoch milliseconds
+//   - duration is rendered as "n MINUTES", "n HOURS" or "n DAYS", for LAST ${window}
+//   - timerange is rendered as the "START x STOP y" clause
+//
+// A parameter cannot be placed inside a quoted literal of the template.
+type AQLTemplate struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	AQL         string            `json:"aql"`
+	Parameters  map[string]string `json:"parameters"`
+
+	once         sync.Once
+	placeholders []aqlPlaceholder
+	compileErr   error
+}
+
+// TimeRange is the value of a timerange parameter.
+type TimeRange struct {
+	Start time.Time
+	Stop  time.Time
+}
+
+type aqlPlaceholder struct {
+	name  string
+	start int
+	end   int
+}
+
+//------------------------------------------------------------------------------
+// Factory
+//------------------------------------------------------------------------------
+
+// NewAQLTemplate returns a new template, after checking that every parameter
+// is declared with a known type and is not placed inside a quoted literal.
+func NewAQLTemplate(name, aql string, parameters map[string]string) (*AQLTemplate, error) {
+	t := &AQLTemplate{
+		Name:       name,
+		AQL:        aql,
+		Parameters: parameters,
+	}
+
+	if err := t.compiled(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// LoadAQLTemplate reads a template from a JSON file.
+func LoadAQLTemplate(path string) (*AQLTemplate, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading the template: %s", err)
+	}
+
+	var t AQLTemplate
+	err = json.Unmarshal(b, &t)
+	if err != nil {
+		return nil, fmt.Errorf("error while unmarshalling the template %s: %s", path, err)
+	}
+
+	if err := t.compiled(); err != nil {
+		return nil, fmt.Errorf("invalid template %s: %s", path, err)
+	}
+
+	return &t, nil
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// Render binds the values to the parameters and returns the AQL query. Every
+// parameter must be bound with a value of its type. A template can be
+// rendered concurrently.
+func (t *AQLTemplate) Render(values map[string]interface{}) (string, error) {
+	if err := t.compiled(); err != nil {
+		return "", err
+	}
+
+	for name := range values {
+		if _, ok := t.Parameters[name]; !ok {
+			return "", fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, placeholder := range t.placeholders {
+		value, ok := values[placeholder.name]
+		if !ok {
+			return "", fmt.Errorf("the parameter %s is not bound", placeholder.name)
+		}
+
+		rendered, err := renderParameter(t.Parameters[placeholder.name], value)
+		if err != nil {
+			return "", fmt.Errorf("invalid value for the parameter %s: %s", placeholder.name, err)
+		}
+
+		b.WriteString(t.AQL[last:placeholder.start])
+		b.WriteString(rendered)
+		last = placeholder.end
+	}
+	b.WriteString(t.AQL[last:])
+
+	return b.String(), nil
+}
+
+// SavedSearchDefinition renders the template into a saved search definition.
+func (t *AQLTemplate) SavedSearchDefinition(values map[string]interface{}) (*SavedSearchDefinition, error) {
+	aql, err := t.Render(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SavedSearchDefinition{
+		Name:        t.Name,
+		Aql:         aql,
+		Description: t.Description,
+	}, nil
+}
+
+// RunTemplate renders the template and runs the query.
+func (endpoint *Endpoint) RunTemplate(ctx context.Context, template *AQLTemplate, values map[string]interface{}, opts *RunOptions) (*ArielResult, error) {
+	aql, err := template.Render(values)
+	if err != nil {
+		return nil, fmt.Errorf("error while rendering the template: %s", err)
+	}
+
+	return endpoint.Run(ctx, aql, opts)
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// compiled compiles the template once, so that the templates which are not
+// created by NewAQLTemplate or LoadAQLTemplate are compiled when first rendered.
+func (t *AQLTemplate) compiled() error {
+	t.once.Do(func() {
+		t.compileErr = t.compile()
+	})
+
+	return t.compileErr
+}
+
+// compile finds the placeholders of the template.
+func (t *AQLTemplate) compile() error {
+	if t.AQL == "" {
+		return fmt.Errorf("the template has no AQL")
+	}
+
+	placeholders := []aqlPlaceholder{}
+	var quote byte
+	for i := 0; i < len(t.AQL); i++ {
+		c := t.AQL[i]
+
+		// Inside a quoted literal
+		if quote != 0 {
+			switch {
+			case c == '\\':
+				i++
+			case c == quote && i+1 < len(t.AQL) && t.AQL[i+1] == quote:
+				i++
+			case c == quote:
+				quote = 0
+			case c == '$' && i+1 < len(t.AQL) && t.AQL[i+1] == '{':
+				return fmt.Errorf("the parameter at offset %d is inside a quoted literal, parameters are quoted when rendered", i)
+			}
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '$' && i+1 < len(t.AQL) && t.AQL[i+1] == '{':
+			end := strings.IndexByte(t.AQL[i:], '}')
+			if end < 0 {
+				return fmt.Errorf("unterminated parameter at offset %d", i)
+			}
+			name := t.AQL[i+2 : i+end]
+			if !aqlIdentifierPattern.MatchString(name) {
+				return fmt.Errorf("invalid parameter name %q", name)
+			}
+			if _, ok := t.Parameters[name]; !ok {
+				return fmt.Errorf("the parameter %s is not declared", name)
+			}
+			placeholders = append(placeholders, aqlPlaceholder{name: name, start: i, end: i + end + 1})
+			i += end
+		}
+	}
+	if quote != 0 {
+		return fmt.Errorf("unterminated literal")
+	}
+
+	for name, kind := range t.Parameters {
+		switch kind {
+		case ParamString, ParamInt, ParamIP, ParamCIDR, ParamTime, ParamDuration, ParamTimeRange:
+		default:
+			return fmt.Errorf("unknown type %s for the parameter %s", kind, name)
+		}
+	}
+
+	t.placeholders = placeholders
+
+	return nil
+}
+
+// renderParameter renders a value of the given type.
+func renderParameter(kind string, value interface{}) (string, error) {
+	switch kind {
+	case ParamString:
+		s, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("expected a string, got %T", value)
+		}
+		return quoteString(s), nil
+	case ParamInt:
+		switch v := value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return fmt.Sprintf("%d", v), nil
+		case string:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("expected an integer, got %q", v)
+			}
+			return strconv.FormatInt(n, 10), nil
+		}
+		return "", fmt.Errorf("expected an integer, got %T", value)
+	case ParamIP:
+		var ip net.IP
+		switch v := value.(type) {
+		case net.IP:
+			ip = v
+		case string:
+			ip = net.ParseIP(v)
+		}
+		if ip == nil {
+			return "", fmt.Errorf("expected an IP, got %v", value)
+		}
+		return quoteString(ip.String()), nil
+	case ParamCIDR:
+		switch v := value.(type) {
+		case *net.IPNet:
+			return quoteString(v.String()), nil
+		case string:
+			_, network, err := net.ParseCIDR(v)
+			if err != nil {
+				return "", fmt.Errorf("expected a CIDR, got %q", v)
+			}
+			return quoteString(network.String()), nil
+		}
+		return "", fmt.Errorf("expected a CIDR, got %T", value)
+	case ParamTime:
+		v, ok := value.(time.Time)
+		if !ok {
+			return "", fmt.Errorf("expected a time, got %T", value)
+		}
+		return strconv.FormatInt(epochMillis(v), 10), nil
+	case ParamDuration:
+		v, ok := value.(time.Duration)
+		if !ok {
+			return "", fmt.Errorf("expected a duration, got %T", value)
+		}
+		return renderDuration(v)
+	case ParamTimeRange:
+		v, ok := value.(TimeRange)
+		if !ok {
+			return "", fmt.Errorf("expected a time range, got %T", value)
+		}
+		if !v.Stop.After(v.Start) {
+			return "", fmt.Errorf("the stop must be after the start")
+		}
+		return "START " + strconv.FormatInt(epochMillis(v.Start), 10) + " STOP " + strconv.FormatInt(epochMillis(v.Stop), 10), nil
+	}
+
+	return "", fmt.Errorf("unknown type %s", kind)
+}
+
+// renderDuration renders the duration with the largest exact unit.
+func renderDuration(d time.Duration) (string, error) {
+	if d <= 0 || d%time.Minute != 0 {
+		return "", fmt.Errorf("expected a positive number of minutes, got %s", d)
+	}
+
+	switch {
+	case d%(24*time.Hour) == 0:
+		return strconv.Itoa(int(d/(24*time.Hour))) + " " + AQLDays, nil
+	case d%time.Hour == 0:
+		return strconv.Itoa(int(d/time.Hour)) + " " + AQLHours, nil
+	}
+
+	return strconv.Itoa(int(d/time.Minute)) + " " + AQLMinutes, nil
+}
@@ -0,0 +1,131 @@
+package goqradar
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAQLTemplateRender(t *testing.T) {
+	template, err := NewAQLTemplate("offense", "SELECT * FROM events WHERE INOFFENSE(${offense}) AND sourceip = ${ip} AND username = ${user} ${range}", map[string]string{
+		"offense": ParamInt,
+		"ip":      ParamIP,
+		"user":    ParamString,
+		"range":   ParamTimeRange,
+	})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	start := time.Unix(1600000000, 0)
+	aql, err := template.Render(map[string]interface{}{
+		"offense": 42,
+		"ip":      "10.0.0.1",
+		"user":    "x' OR '1'='1",
+		"range":   TimeRange{Start: start, Stop: start.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	expected := `SELECT * FROM events WHERE INOFFENSE(42) AND sourceip = '10.0.0.1' AND username = 'x'' OR ''1''=''1' START 1600000000000 STOP 1600003600000`
+	if aql != expected {
+		t.Fatalf("unexpected query:\n%s\n%s", aql, expected)
+	}
+}
+
+func TestAQLTemplateErrors(t *testing.T) {
+	if _, err := NewAQLTemplate("quoted", "SELECT * FROM events WHERE username = '${user}'", map[string]string{"user": ParamString}); err == nil {
+		t.Fatal("should error with a parameter inside a literal")
+	}
+	if _, err := NewAQLTemplate("undeclared", "SELECT * FROM events WHERE qid = ${qid}", nil); err == nil {
+		t.Fatal("should error with an undeclared parameter")
+	}
+
+	template, err := NewAQLTemplate("last", "SELECT * FROM events WHERE sourceip = ${ip} LAST ${window}", map[string]string{
+		"ip":     ParamIP,
+		"window": ParamDuration,
+	})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if _, err := template.Render(map[string]interface{}{"window": time.Hour}); err == nil {
+		t.Fatal("should error with an unbound parameter")
+	}
+	if _, err := template.Render(map[string]interface{}{"ip": "10.0.0.1' OR 1=1", "window": time.Hour}); err == nil {
+		t.Fatal("should error with an invalid IP")
+	}
+
+	aql, err := template.Render(map[string]interface{}{"ip": "10.0.0.1", "window": 2 * time.Hour})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if aql != "SELECT * FROM events WHERE sourceip = '10.0.0.1' LAST 2 HOURS" {
+		t.Fatalf("unexpected query: %s", aql)
+	}
+}
+
+func TestLoadAQLTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goqradar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "template.json")
+	err = ioutil.WriteFile(path, []byte(`{"name":"user","aql":"SELECT * FROM events WHERE username = ${user}","parameters":{"user":"string"}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template, err := LoadAQLTemplate(path)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	definition, err := template.SavedSearchDefinition(map[string]interface{}{"user": "admin"})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if definition.Name != "user" || definition.Aql != "SELECT * FROM events WHERE username = 'admin'" {
+		t.Fatalf("unexpected definition: %+v", definition)
+	}
+
+	// An empty template is invalid
+	if err := ioutil.WriteFile(path, []byte("null"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAQLTemplate(path); err == nil {
+		t.Fatal("should error with an empty template")
+	}
+}
+
+func TestAQLTemplateRenderConcurrently(t *testing.T) {
+	// A template which is not created by NewAQLTemplate is compiled when first rendered
+	template := &AQLTemplate{
+		Name:       "qid",
+		AQL:        "SELECT * FROM events WHERE qid = ${qid}",
+		Parameters: map[string]string{"qid": ParamInt},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			aql, err := template.Render(map[string]interface{}{"qid": i})
+			if err != nil {
+				t.Errorf("should not error but error is: %s", err)
+				return
+			}
+			if aql != "SELECT * FROM events WHERE qid = "+strconv.Itoa(i) {
+				t.Errorf("unexpected query: %s", aql)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -45,6 +45,16 @@ type SavedSearchDependentTask struct {
 	TaskComponents     []TaskComponents `json:"task_components"`
 }
 
+// SavedSearchDefinition holds the editable fields of an Ariel saved search.
+type SavedSearchDefinition struct {
+	Name          string `json:"name"`
+	Aql           string `json:"aql"`
+	Description   string `json:"description,omitempty"`
+	IsDashboard   bool   `json:"is_dashboard"`
+	IsQuickSearch bool   `json:"is_quick_search"`
+	IsShared      bool   `json:"is_shared"`
+}
+
 // TaskComponents is a QRadar TaskComponents
 type TaskComponents struct {
 	Completed          int    `json:"completed"`
@@ -106,6 +116,7 @@ type Snapshot struct {
 
 // Database is a QRadar database
 type Database struct {
+	Name    string    `json:"name"`
 	Total   int       `json:"total"`
 	Min     int       `json:"min"`
 	Max     int       `json:"max"`
@@ -122,12 +133,17 @@ type Columns struct {
 	ProviderName    string `json:"provider_name"`
 }
 
+// SearchMetadata is the metadata of the columns returned by an Ariel search.
+type SearchMetadata struct {
+	Columns []Columns `json:"columns"`
+}
+
 // SavedSearchPaginatedResponse is the paginated response.
 type SavedSearchPaginatedResponse struct {
 	Total       int            `json:"total"`
 	Min         int            `json:"min"`
 	Max         int            `json:"max"`
-	SavedSearch []*SavedSearch `json:"offenses"`
+	SavedSearch []*SavedSearch `json:"saved_searches"`
 }
 
 // SearchesPaginatedResponse is the paginated response.
@@ -138,17 +154,25 @@ type SearchesPaginatedResponse struct {
 	Searches []*Searches `json:"offenses"`
 }
 
+// SearchUpdate is the update of an Ariel search.
+type SearchUpdate struct {
+	Status                   string
+	SaveResults              *bool
+	DesiredRetentionTimeMsec *int
+}
+
 // DatabasePaginatedResponse is the paginated response.
 type DatabasePaginatedResponse struct {
 	Total     int      `json:"total"`
 	Min       int      `json:"min"`
 	Max       int      `json:"max"`
-	Databases []string `json:"offenses"`
+	Databases []string `json:"databases"`
 }
 
 // SearchesResult is the result of an AQL
 type SearchesResult struct {
 	Events []interface{} `json:"events"`
+	Flows  []interface{} `json:"flows"`
 }
 
 // SearchesResultsPaginatedResponse is the paginated response.
@@ -292,6 +316,7 @@ func (endpoint *Endpoint) GetSearchesID(ctx context.Context, searchID string, pr
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
@@ -376,34 +401,38 @@ func (endpoint *Endpoint) GetDatabase(ctx context.Context, databaseName string,
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
 	}
 
-	// Process the Content-Range
-	min, max, total, err := parseContentRange(resp.Header.Get("Content-Range"))
-	if err != nil {
-		return nil, fmt.Errorf("error while parsing the content-range: %s", err)
-	}
-
 	// Prepare the response
 	response := &Database{
-		Total: total,
-		Min:   min,
-		Max:   max,
+		Name: databaseName,
 	}
 
-	// Decode the response
-	err = json.NewDecoder(resp.Body).Decode(&response.Columns)
+	// Decode the response, an object holding the columns
+	err = json.NewDecoder(resp.Body).Decode(&response)
 	if err != nil {
 		return nil, fmt.Errorf("error while decoding the response: %s", err)
 	}
 
+	// Process the Content-Range, which is only sent when the columns are paginated
+	if resp.Header.Get("Content-Range") == "" {
+		response.Total = len(response.Columns)
+		response.Max = len(response.Columns) - 1
+		return response, nil
+	}
+	response.Min, response.Max, response.Total, err = parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing the content-range: %s", err)
+	}
+
 	return response, nil
 }
 
-// ListDatabase retrieves the list of Ariel saved search
+// ListDatabase retrieves the list of Ariel databases
 func (endpoint *Endpoint) ListDatabase(ctx context.Context, filter string, min, max int) (*DatabasePaginatedResponse, error) {
 	// Options
 	options := []Option{}
@@ -417,6 +446,7 @@ func (endpoint *Endpoint) ListDatabase(ctx context.Context, filter string, min,
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
@@ -468,8 +498,9 @@ func (endpoint *Endpoint) PostSearches(ctx context.Context, queryExpression stri
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
 	}
 
@@ -503,6 +534,7 @@ func (endpoint *Endpoint) GetSearchesResults(ctx context.Context, searchID strin
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
@@ -519,3 +551,242 @@ func (endpoint *Endpoint) GetSearchesResults(ctx context.Context, searchID strin
 
 	return response, nil
 }
+
+// UpdateSearch updates the status, the save_results flag or the retention of an Ariel search.
+func (endpoint *Endpoint) UpdateSearch(ctx context.Context, searchID string, update *SearchUpdate) (*Searches, error) {
+	if update == nil {
+		return nil, fmt.Errorf("the update is required")
+	}
+
+	// Options
+	options := []Option{}
+	if update.Status != "" {
+		options = append(options, WithParam("status", update.Status))
+	}
+	if update.SaveResults != nil {
+		options = append(options, WithParam("save_results", strconv.FormatBool(*update.SaveResults)))
+	}
+	if update.DesiredRetentionTimeMsec != nil {
+		options = append(options, WithParam("desired_retention_time_msec", strconv.Itoa(*update.DesiredRetentionTimeMsec)))
+	}
+
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodPost, "/ariel/searches/"+searchID, options...)
+	if err != nil {
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	// Prepare the response
+	var response *Searches
+
+	// Decode the response
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding the response: %s", err)
+	}
+
+	return response, nil
+}
+
+// CancelSearch cancels an Ariel search.
+func (endpoint *Endpoint) CancelSearch(ctx context.Context, searchID string) (*Searches, error) {
+	return endpoint.UpdateSearch(ctx, searchID, &SearchUpdate{Status: SearchStatusCanceled})
+}
+
+// DeleteSearch deletes an Ariel search and its results.
+func (endpoint *Endpoint) DeleteSearch(ctx context.Context, searchID string) (*Searches, error) {
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodDelete, "/ariel/searches/"+searchID)
+	if err != nil {
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 && resp.StatusCode != 202 {
+		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	// Prepare the response
+	var response *Searches
+
+	// Decode the response
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding the response: %s", err)
+	}
+
+	return response, nil
+}
+
+// CreateSavedSearch creates an Ariel saved search.
+func (endpoint *Endpoint) CreateSavedSearch(ctx context.Context, definition *SavedSearchDefinition, fields string) (*SavedSearch, error) {
+	// Options
+	options := []Option{WithData(definition)}
+	if fields != "" {
+		options = append(options, WithParam("fields", fields))
+	}
+
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodPost, "/ariel/saved_searches", options...)
+	if err != nil {
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 201 {
+		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	// Prepare the response
+	var response *SavedSearch
+
+	// Decode the response
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding the response: %s", err)
+	}
+
+	return response, nil
+}
+
+// UpdateSavedSearch updates an Ariel saved search.
+func (endpoint *Endpoint) UpdateSavedSearch(ctx context.Context, id int, definition *SavedSearchDefinition, fields string) (*SavedSearch, error) {
+	// Options
+	options := []Option{WithData(definition)}
+	if fields != "" {
+		options = append(options, WithParam("fields", fields))
+	}
+
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodPost, "/ariel/saved_searches/"+strconv.Itoa(id), options...)
+	if err != nil {
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	// Prepare the response
+	var response *SavedSearch
+
+	// Decode the response
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding the response: %s", err)
+	}
+
+	return response, nil
+}
+
+// DeleteSavedSearch starts the deletion of an Ariel saved search and returns the delete task.
+func (endpoint *Endpoint) DeleteSavedSearch(ctx context.Context, id int, fields string) (*SavedSearchDependentTask, error) {
+	// Options
+	options := []Option{}
+	if fields != "" {
+		options = append(options, WithParam("fields", fields))
+	}
+
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodDelete, "/ariel/saved_searches/"+strconv.Itoa(id), options...)
+	if err != nil {
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 202 {
+		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	// Prepare the response
+	var response *SavedSearchDependentTask
+
+	// Decode the response
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding the response: %s", err)
+	}
+
+	return response, nil
+}
+
+// GetSavedSearchDeleteTask retrieves the status of a saved search delete task.
+func (endpoint *Endpoint) GetSavedSearchDeleteTask(ctx context.Context, taskID int, fields string) (*SavedSearchDependentTask, error) {
+	// Options
+	options := []Option{}
+	if fields != "" {
+		options = append(options, WithParam("fields", fields))
+	}
+
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodGet, "/ariel/saved_search_delete_tasks/"+strconv.Itoa(taskID), options...)
+	if err != nil {
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	// Prepare the response
+	var response *SavedSearchDependentTask
+
+	// Decode the response
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding the response: %s", err)
+	}
+
+	return response, nil
+}
+
+// GetSearchMetadata retrieves the columns returned by the Ariel search that is identified by the search ID
+func (endpoint *Endpoint) GetSearchMetadata(ctx context.Context, searchID string) (*SearchMetadata, error) {
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodGet, "/ariel/searches/"+searchID+"/metadata")
+	if err != nil {
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	// Read the respsonse
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading the request : %s", err)
+	}
+
+	// The columns are either at the root or under the name of the database
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(body, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("Error while unmarshalling the response : %s. HTTP response is : %s", err, string(body))
+	}
+	for _, key := range []string{"events", "flows"} {
+		if nested, ok := raw[key]; ok {
+			body = nested
+			break
+		}
+	}
+
+	// Prepare the response
+	var response *SearchMetadata
+
+	// Unmarshal the response
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, fmt.Errorf("Error while unmarshalling the response : %s. HTTP response is : %s", err, string(body))
+	}
+
+	return response, nil
+}
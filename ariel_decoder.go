@@ -0,0 +1,317 @@
+package goqradar
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	ipType   = reflect.TypeOf(net.IP{})
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// DecodeReport reports the columns and the fields that did not match.
+type DecodeReport struct {
+	// UnknownColumns are the columns without a matching field.
+	UnknownColumns []string
+
+	// MissingColumns are the tagged fields without a matching column.
+	MissingColumns []string
+}
+
+// DecodeError is returned when a column cannot be decoded into its field.
+type DecodeError struct {
+	Row    int
+	Column string
+	Err    error
+}
+
+// Error returns the position and the cause of the error.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("error while decoding the column %s of the row %d: %s", e.Column, e.Row, e.Err)
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// Decode decodes the rows of the result into dst, which must be a pointer to a slice of structs.
+func (r *ArielResult) Decode(dst interface{}) (*DecodeReport, error) {
+	return DecodeRows(r.Rows, dst)
+}
+
+// DecodeRows decodes the rows into dst, which must be a pointer to a slice of
+// structs or of pointers to structs. The columns are mapped onto the fields
+// with the `aql` tag, such as `aql:"sourceip"`.
+func DecodeRows(rows []map[string]interface{}, dst interface{}) (*DecodeReport, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("destination must be a pointer to a slice, got %T", dst)
+	}
+	slice := v.Elem()
+
+	// Find the struct type
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	structType := elemType
+	if isPtr {
+		structType = elemType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("destination must be a slice of structs, got %T", dst)
+	}
+
+	fields := aqlFields(structType)
+	report := newDecodeReport(rows, fields)
+
+	for i, row := range rows {
+		item := reflect.New(structType)
+		if err := decodeRow(row, item.Elem(), fields); err != nil {
+			err.Row = i
+			return report, err
+		}
+
+		if isPtr {
+			slice.Set(reflect.Append(slice, item))
+		} else {
+			slice.Set(reflect.Append(slice, item.Elem()))
+		}
+	}
+
+	return report, nil
+}
+
+// DecodeRow decodes a single row into dst, which must be a pointer to a struct.
+func DecodeRow(row map[string]interface{}, dst interface{}) (*DecodeReport, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("destination must be a pointer to a struct, got %T", dst)
+	}
+
+	fields := aqlFields(v.Elem().Type())
+	report := newDecodeReport([]map[string]interface{}{row}, fields)
+	if err := decodeRow(row, v.Elem(), fields); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// aqlFields returns the index of the fields by column name.
+func aqlFields(t reflect.Type) map[string]int {
+	fields := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("aql")
+		if tag == "" || tag == "-" || f.PkgPath != "" {
+			continue
+		}
+		fields[tag] = i
+	}
+
+	return fields
+}
+
+func newDecodeReport(rows []map[string]interface{}, fields map[string]int) *DecodeReport {
+	report := &DecodeReport{}
+	seen := map[string]bool{}
+
+	for _, row := range rows {
+		for column := range row {
+			if seen[column] {
+				continue
+			}
+			seen[column] = true
+
+			if _, ok := fields[column]; !ok {
+				report.UnknownColumns = append(report.UnknownColumns, column)
+			}
+		}
+	}
+
+	if len(rows) > 0 {
+		for column := range fields {
+			if !seen[column] {
+				report.MissingColumns = append(report.MissingColumns, column)
+			}
+		}
+	}
+
+	sort.Strings(report.UnknownColumns)
+	sort.Strings(report.MissingColumns)
+
+	return report
+}
+
+func decodeRow(row map[string]interface{}, v reflect.Value, fields map[string]int) *DecodeError {
+	for column, index := range fields {
+		value, ok := row[column]
+		if !ok {
+			continue
+		}
+
+		if err := setValue(v.Field(index), value); err != nil {
+			return &DecodeError{Column: column, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// setValue coerces the JSON value into the field.
+func setValue(field reflect.Value, value interface{}) error {
+	// Nullable columns
+	if field.Kind() == reflect.Ptr {
+		if value == nil {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+
+		ptr := reflect.New(field.Type().Elem())
+		if err := setValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	switch field.Type() {
+	case ipType:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("cannot convert %T into an IP", value)
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return fmt.Errorf("invalid IP: %s", s)
+		}
+		field.Set(reflect.ValueOf(ip))
+		return nil
+	case timeType:
+		ms, err := toFloat(value)
+		if err != nil {
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("cannot convert %T into a time", value)
+			}
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return fmt.Errorf("invalid time: %s", s)
+			}
+			field.Set(reflect.ValueOf(t))
+			return nil
+		}
+		field.Set(reflect.ValueOf(time.Unix(0, int64(ms)*int64(time.Millisecond)).UTC()))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Interface:
+		field.Set(reflect.ValueOf(value))
+	case reflect.String:
+		switch v := value.(type) {
+		case string:
+			field.SetString(v)
+		case float64:
+			field.SetString(strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			field.SetString(strconv.FormatBool(v))
+		default:
+			return fmt.Errorf("cannot convert %T into a string", value)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, err := toInteger(value)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(int64(f)) {
+			return fmt.Errorf("%v overflows %s", value, field.Type())
+		}
+		field.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, err := toInteger(value)
+		if err != nil {
+			return err
+		}
+		if f < 0 || field.OverflowUint(uint64(f)) {
+			return fmt.Errorf("%v overflows %s", value, field.Type())
+		}
+		field.SetUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(value)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		switch v := value.(type) {
+		case bool:
+			field.SetBool(v)
+		case float64:
+			field.SetBool(v != 0)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid boolean: %s", v)
+			}
+			field.SetBool(b)
+		default:
+			return fmt.Errorf("cannot convert %T into a boolean", value)
+		}
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
+
+// toFloat converts a JSON number or a numeric string.
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number: %s", v)
+		}
+		return f, nil
+	}
+
+	return 0, fmt.Errorf("cannot convert %T into a number", value)
+}
+
+// toInteger converts a JSON number or a numeric string without fractional part.
+func toInteger(value interface{}) (float64, error) {
+	f, err := toFloat(value)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("%v is not an integer", value)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,101 @@
+package goqradar
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+type decodedEvent struct {
+	SourceIP   string    `aql:"sourceip"`
+	SourcePort int       `aql:"sourceport"`
+	StartTime  time.Time `aql:"starttime"`
+	Username   *string   `aql:"username"`
+	Magnitude  *int      `aql:"magnitude"`
+	Ignored    string    `aql:"-"`
+	Count      float64   `aql:"Event Count"`
+}
+
+func TestDecodeRows(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"sourceip": "10.0.0.1", "sourceport": float64(443), "starttime": float64(1600000000000), "username": nil, "magnitude": "5", "Event Count": float64(3), "extra": "x"},
+	}
+
+	var events []decodedEvent
+	report, err := DecodeRows(rows, &events)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("should have one event, got %d", len(events))
+	}
+	e := events[0]
+	if e.SourceIP != "10.0.0.1" || e.SourcePort != 443 || e.Count != 3 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if !e.StartTime.Equal(time.Unix(1600000000, 0)) {
+		t.Fatalf("unexpected time: %s", e.StartTime)
+	}
+	if e.Username != nil {
+		t.Fatal("username should be nil")
+	}
+	if e.Magnitude == nil || *e.Magnitude != 5 {
+		t.Fatal("magnitude should be 5")
+	}
+	if len(report.UnknownColumns) != 1 || report.UnknownColumns[0] != "extra" {
+		t.Fatalf("unexpected unknown columns: %v", report.UnknownColumns)
+	}
+}
+
+func TestDecodeRowsError(t *testing.T) {
+	var events []*decodedEvent
+	_, err := DecodeRows([]map[string]interface{}{{"sourceport": "abc"}}, &events)
+	if _, ok := err.(*DecodeError); !ok {
+		t.Fatalf("should be a decode error, got: %v", err)
+	}
+}
+
+func TestDecodeRowsIP(t *testing.T) {
+	type flow struct {
+		SourceIP      net.IP  `aql:"sourceip"`
+		DestinationIP *net.IP `aql:"destinationip"`
+	}
+
+	var flows []flow
+	_, err := DecodeRows([]map[string]interface{}{
+		{"sourceip": "10.0.0.1", "destinationip": "2001:db8::1"},
+		{"sourceip": "10.0.0.2", "destinationip": nil},
+	}, &flows)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if !flows[0].SourceIP.Equal(net.ParseIP("10.0.0.1")) || !flows[0].DestinationIP.Equal(net.ParseIP("2001:db8::1")) {
+		t.Fatalf("unexpected flow: %+v", flows[0])
+	}
+	if flows[1].DestinationIP != nil {
+		t.Fatal("destination IP should be nil")
+	}
+
+	if _, err := DecodeRows([]map[string]interface{}{{"sourceip": "10.0.0.300"}}, &flows); err == nil {
+		t.Fatal("should error with an invalid IP")
+	}
+	if _, err := DecodeRows([]map[string]interface{}{{"sourceip": float64(42)}}, &flows); err == nil {
+		t.Fatal("should error with a number")
+	}
+}
+
+func TestDecodeRowsFractionalInteger(t *testing.T) {
+	var events []decodedEvent
+	if _, err := DecodeRows([]map[string]interface{}{{"sourceport": float64(443.5)}}, &events); err == nil {
+		t.Fatal("should error with a fractional number")
+	}
+	if _, err := DecodeRows([]map[string]interface{}{{"magnitude": "5.5"}}, &events); err == nil {
+		t.Fatal("should error with a fractional numeric string")
+	}
+
+	// The float fields keep the fractional part
+	if _, err := DecodeRows([]map[string]interface{}{{"Event Count": float64(1.5)}}, &events); err != nil || events[0].Count != 1.5 {
+		t.Fatalf("unexpected count %v, error is: %v", events[0].Count, err)
+	}
+}
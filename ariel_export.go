@@ -0,0 +1,322 @@
+package goqradar
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// Export formats.
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+const checkpointSuffix = ".checkpoint"
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// ExportOptions are the options of an export of Ariel results.
+type ExportOptions struct {
+	// Format is either "csv" or "ndjson". Default is CSV.
+	Format string
+
+	// Columns is the order of the CSV columns. Default is the order of the search metadata.
+	Columns []string
+
+	// PageSize is the number of rows fetched per request. Default is 1000.
+	PageSize int
+
+	// Gzip compresses the output. Every page is written as a gzip member, so
+	// that an interrupted export can be resumed.
+	Gzip bool
+
+	// Offset is the index of the first row to export.
+	Offset int
+
+	// Progress is called after every page with the offset of the next row.
+	Progress func(offset int)
+}
+
+type exportCheckpoint struct {
+	SearchID string `json:"search_id"`
+	Format   string `json:"format"`
+	Gzip     bool   `json:"gzip"`
+	Offset   int    `json:"offset"`
+	Size     int64  `json:"size"`
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// ExportResults streams the results of a completed search into the writer,
+// page by page. It returns the offset of the next row to export, which can be
+// used to resume the export after an error.
+func (endpoint *Endpoint) ExportResults(ctx context.Context, searchID string, w io.Writer, opts *ExportOptions) (int, error) {
+	return endpoint.export(ctx, searchID, w, opts, nil)
+}
+
+// ExportResultsToFile exports the results of a completed search into the
+// file. The progress is checkpointed next to the file, so that an interrupted
+// export of the same search continues where it stopped. The export must be
+// resumed with the same format and compression. If the file was removed, the
+// export starts over.
+func (endpoint *Endpoint) ExportResultsToFile(ctx context.Context, searchID, path string, opts *ExportOptions) (int, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	resumed := *opts
+	if resumed.Format == "" {
+		resumed.Format = ExportFormatCSV
+	}
+	checkpointPath := path + checkpointSuffix
+
+	// Resume from the checkpoint
+	checkpoint, err := readExportCheckpoint(checkpointPath)
+	if err != nil {
+		return opts.Offset, err
+	}
+	if checkpoint != nil && checkpoint.SearchID != searchID {
+		checkpoint = nil
+	}
+	if checkpoint != nil {
+		if checkpoint.Format != resumed.Format || checkpoint.Gzip != resumed.Gzip {
+			return opts.Offset, fmt.Errorf("the export was started with the format %s and gzip %t, it cannot be resumed with the format %s and gzip %t", checkpoint.Format, checkpoint.Gzip, resumed.Format, resumed.Gzip)
+		}
+
+		info, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err):
+			checkpoint = nil
+		case err != nil:
+			return opts.Offset, fmt.Errorf("error while reading the file: %s", err)
+		case info.Size() < checkpoint.Size:
+			return opts.Offset, fmt.Errorf("the file is shorter than its checkpoint, remove the checkpoint to start over")
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if checkpoint != nil {
+		flags = os.O_WRONLY
+		resumed.Offset = checkpoint.Offset
+	}
+
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return resumed.Offset, fmt.Errorf("error while opening the file: %s", err)
+	}
+	defer f.Close()
+
+	// Drop what was written after the checkpoint
+	if checkpoint != nil {
+		if err := f.Truncate(checkpoint.Size); err != nil {
+			return resumed.Offset, fmt.Errorf("error while truncating the file: %s", err)
+		}
+		if _, err := f.Seek(checkpoint.Size, io.SeekStart); err != nil {
+			return resumed.Offset, fmt.Errorf("error while seeking the file: %s", err)
+		}
+	}
+
+	offset, err := endpoint.export(ctx, searchID, f, &resumed, func(offset int) error {
+		size, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("error while seeking the file: %s", err)
+		}
+
+		return writeExportCheckpoint(checkpointPath, &exportCheckpoint{
+			SearchID: searchID,
+			Format:   resumed.Format,
+			Gzip:     resumed.Gzip,
+			Offset:   offset,
+			Size:     size,
+		})
+	})
+	if err != nil {
+		return offset, err
+	}
+
+	// The export is complete
+	if err := os.Remove(checkpointPath); err != nil && !os.IsNotExist(err) {
+		return offset, fmt.Errorf("error while removing the checkpoint: %s", err)
+	}
+
+	return offset, nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+func (endpoint *Endpoint) export(ctx context.Context, searchID string, w io.Writer, opts *ExportOptions, afterPage func(int) error) (int, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	format := opts.Format
+	if format == "" {
+		format = ExportFormatCSV
+	}
+	if format != ExportFormatCSV && format != ExportFormatNDJSON {
+		return opts.Offset, fmt.Errorf("invalid export format: %s", format)
+	}
+
+	// Retrieve the number of records
+	search, err := endpoint.GetSearchesID(ctx, searchID, "")
+	if err != nil {
+		return opts.Offset, fmt.Errorf("error while retrieving the search: %s", err)
+	}
+	if search.Status != SearchStatusCompleted {
+		return opts.Offset, fmt.Errorf("the search %s is not completed: %s", searchID, search.Status)
+	}
+
+	// Retrieve the order of the columns
+	columns := opts.Columns
+	if format == ExportFormatCSV && len(columns) == 0 {
+		metadata, err := endpoint.GetSearchMetadata(ctx, searchID)
+		if err != nil {
+			return opts.Offset, fmt.Errorf("error while retrieving the search metadata: %s", err)
+		}
+		columns = metadata.ColumnNames()
+	}
+
+	// Open a compressed member on the writer
+	compress := func(w io.Writer, write func(io.Writer) error) error {
+		if !opts.Gzip {
+			return write(w)
+		}
+
+		gw := gzip.NewWriter(w)
+		if err := write(gw); err != nil {
+			return err
+		}
+		if err := gw.Close(); err != nil {
+			return fmt.Errorf("error while compressing: %s", err)
+		}
+
+		return nil
+	}
+
+	// Write the header
+	offset := opts.Offset
+	if format == ExportFormatCSV && offset == 0 {
+		err = compress(w, func(out io.Writer) error {
+			return writeCSVRecords(out, [][]string{columns})
+		})
+		if err != nil {
+			return offset, err
+		}
+	}
+
+	err = endpoint.fetchResults(ctx, searchID, offset, search.RecordCount, opts.PageSize, func(_ string, rows []map[string]interface{}) error {
+		err := compress(w, func(out io.Writer) error {
+			if format == ExportFormatCSV {
+				return writeCSVPage(out, columns, rows)
+			}
+			return writeNDJSONPage(out, rows)
+		})
+		if err != nil {
+			return err
+		}
+
+		offset += len(rows)
+		if afterPage != nil {
+			if err := afterPage(offset); err != nil {
+				return err
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(offset)
+		}
+
+		return nil
+	})
+
+	return offset, err
+}
+
+func writeCSVPage(w io.Writer, columns []string, rows []map[string]interface{}) error {
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = formatCSVValue(row[column])
+		}
+		records = append(records, record)
+	}
+
+	return writeCSVRecords(w, records)
+}
+
+func writeCSVRecords(w io.Writer, records [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("error while writing the rows: %s", err)
+	}
+
+	return nil
+}
+
+func writeNDJSONPage(w io.Writer, rows []map[string]interface{}) error {
+	encoder := json.NewEncoder(w)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return fmt.Errorf("error while writing the row: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(b)
+}
+
+func readExportCheckpoint(path string) (*exportCheckpoint, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while reading the checkpoint: %s", err)
+	}
+
+	var checkpoint *exportCheckpoint
+	err = json.Unmarshal(b, &checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("error while unmarshalling the checkpoint: %s", err)
+	}
+
+	return checkpoint, nil
+}
+
+func writeExportCheckpoint(path string, checkpoint *exportCheckpoint) error {
+	if err := writeJSONFile(path, checkpoint); err != nil {
+		return fmt.Errorf("error while writing the checkpoint: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package goqradar
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportResultsToFile(t *testing.T) {
+	server := fakeArielServer(t, 25, "events")
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	ctx := context.Background()
+	_, err := client.Ariel.Run(ctx, "SELECT id FROM events", &RunOptions{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	dir, err := ioutil.TempDir("", "goqradar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "export.csv.gz")
+
+	// Simulate an export interrupted after the first page
+	stop, cancel := context.WithCancel(ctx)
+	offset, err := client.Ariel.ExportResultsToFile(stop, "s1", path, &ExportOptions{
+		PageSize: 10,
+		Gzip:     true,
+		Progress: func(int) { cancel() },
+	})
+	if err == nil || offset != 10 {
+		t.Fatalf("export should be interrupted at 10, got %d: %v", offset, err)
+	}
+
+	// Resume the export
+	offset, err = client.Ariel.ExportResultsToFile(ctx, "s1", path, &ExportOptions{PageSize: 10, Gzip: true})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if offset != 25 {
+		t.Fatalf("should have exported 25 rows, got %d", offset)
+	}
+	if _, err := os.Stat(path + checkpointSuffix); !os.IsNotExist(err) {
+		t.Fatal("checkpoint should be removed")
+	}
+
+	// Read the file
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(gr).ReadAll()
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if len(records) != 26 || records[0][0] != "id" || records[25][0] != "24" {
+		t.Fatalf("unexpected records: %v", records)
+	}
+}
+
+func TestExportResultsToFileResume(t *testing.T) {
+	server := fakeArielServer(t, 25, "events")
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	ctx := context.Background()
+	_, err := client.Ariel.Run(ctx, "SELECT id FROM events", &RunOptions{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	dir, err := ioutil.TempDir("", "goqradar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "export.csv")
+
+	interrupt := func() {
+		stop, cancel := context.WithCancel(ctx)
+		_, err := client.Ariel.ExportResultsToFile(stop, "s1", path, &ExportOptions{
+			PageSize: 10,
+			Progress: func(int) { cancel() },
+		})
+		if err == nil {
+			t.Fatal("export should be interrupted")
+		}
+	}
+
+	// The options cannot change on resume
+	interrupt()
+	if _, err := client.Ariel.ExportResultsToFile(ctx, "s1", path, &ExportOptions{PageSize: 10, Gzip: true}); err == nil {
+		t.Fatal("should error when resuming with gzip")
+	}
+	if _, err := client.Ariel.ExportResultsToFile(ctx, "s1", path, &ExportOptions{PageSize: 10, Format: ExportFormatNDJSON}); err == nil {
+		t.Fatal("should error when resuming with another format")
+	}
+
+	// The export starts over without the file
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	offset, err := client.Ariel.ExportResultsToFile(ctx, "s1", path, &ExportOptions{PageSize: 10, Format: ExportFormatCSV})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if offset != 25 {
+		t.Fatalf("should have exported 25 rows, got %d", offset)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if len(records) != 26 || records[0][0] != "id" || records[1][0] != "0" {
+		t.Fatalf("unexpected records: %v", records)
+	}
+}
@@ -0,0 +1,228 @@
+package goqradar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Ariel search statuses.
+const (
+	SearchStatusWait      = "WAIT"
+	SearchStatusExecute   = "EXECUTE"
+	SearchStatusSorting   = "SORTING"
+	SearchStatusCompleted = "COMPLETED"
+	SearchStatusCanceled  = "CANCELED"
+	SearchStatusError     = "ERROR"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultRunTimeout   = 10 * time.Minute
+	defaultPageSize     = 1000
+	cancelTimeout       = 10 * time.Second
+)
+
+// ErrSearchTimeout is returned when a search is not completed before the timeout.
+var ErrSearchTimeout = errors.New("timeout while waiting for the search to complete")
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// RunOptions are the options of an Ariel query run.
+type RunOptions struct {
+	// PollInterval is the interval between two status checks. Default is 1 second.
+	PollInterval time.Duration
+
+	// Timeout is the maximum time to wait for the search to complete. Default is 10 minutes.
+	Timeout time.Duration
+
+	// PageSize is the number of rows fetched per request. Default is 1000.
+	PageSize int
+}
+
+// ArielResult is the result of an Ariel query run.
+type ArielResult struct {
+	SearchID string
+	Search   *Searches
+
+	// Database is the key of the results, either "events" or "flows".
+	Database string
+	Rows     []map[string]interface{}
+}
+
+// SearchError is returned when a search ends in error or is canceled.
+type SearchError struct {
+	SearchID string
+	Status   string
+	Messages []ErrorMessages
+}
+
+// Error returns the error messages of the search.
+func (e *SearchError) Error() string {
+	messages := []string{}
+	for _, m := range e.Messages {
+		messages = append(messages, fmt.Sprintf("%s (%s)", m.Message, m.Code))
+	}
+
+	if len(messages) == 0 {
+		return fmt.Sprintf("search %s ended with status %s", e.SearchID, e.Status)
+	}
+
+	return fmt.Sprintf("search %s ended with status %s: %s", e.SearchID, e.Status, strings.Join(messages, ", "))
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// Run submits the AQL query, waits for the search to complete and fetches all the results.
+func (endpoint *Endpoint) Run(ctx context.Context, aql string, opts *RunOptions) (*ArielResult, error) {
+	// Submit the query
+	search, err := endpoint.PostSearches(ctx, aql, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the search: %s", err)
+	}
+
+	return endpoint.runSearch(ctx, search.SearchID, opts)
+}
+
+// runSearch waits for the given search and fetches all the results.
+func (endpoint *Endpoint) runSearch(ctx context.Context, searchID string, opts *RunOptions) (*ArielResult, error) {
+	if opts == nil {
+		opts = &RunOptions{}
+	}
+
+	// Wait for the search
+	search, err := endpoint.waitSearch(ctx, searchID, opts)
+	if err != nil {
+		if ctx.Err() != nil || err == ErrSearchTimeout {
+			endpoint.cancelAbandonedSearch(searchID)
+		}
+		return nil, err
+	}
+
+	// Fetch the results
+	result := &ArielResult{
+		SearchID: searchID,
+		Search:   search,
+		Rows:     []map[string]interface{}{},
+	}
+	err = endpoint.fetchResults(ctx, searchID, 0, search.RecordCount, opts.PageSize, func(database string, rows []map[string]interface{}) error {
+		result.Database = database
+		result.Rows = append(result.Rows, rows...)
+		return nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			endpoint.cancelAbandonedSearch(searchID)
+		}
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// cancelAbandonedSearch cancels a search whose caller is gone, so that it does
+// not keep consuming resources on the console.
+func (endpoint *Endpoint) cancelAbandonedSearch(searchID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+
+	endpoint.CancelSearch(ctx, searchID)
+}
+
+// waitSearch polls the search until it is completed.
+func (endpoint *Endpoint) waitSearch(ctx context.Context, searchID string, opts *RunOptions) (*Searches, error) {
+	pollInterval := opts.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultRunTimeout
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		search, err := endpoint.GetSearchesID(ctx, searchID, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while retrieving the search status: %s", err)
+		}
+
+		switch search.Status {
+		case SearchStatusCompleted:
+			if hasErrorMessages(search.ErrorMessages) {
+				return nil, &SearchError{SearchID: searchID, Status: search.Status, Messages: search.ErrorMessages}
+			}
+			return search, nil
+		case SearchStatusError, SearchStatusCanceled:
+			return nil, &SearchError{SearchID: searchID, Status: search.Status, Messages: search.ErrorMessages}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline.C:
+			return nil, ErrSearchTimeout
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// fetchResults pages through the results of a completed search, from the given offset.
+func (endpoint *Endpoint) fetchResults(ctx context.Context, searchID string, offset, recordCount, pageSize int, fn func(string, []map[string]interface{}) error) error {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	for min := offset; min < recordCount; min += pageSize {
+		max := min + pageSize - 1
+		if max >= recordCount {
+			max = recordCount - 1
+		}
+
+		page, err := endpoint.GetSearchesResults(ctx, searchID, min, max)
+		if err != nil {
+			return fmt.Errorf("error while retrieving the results [%d-%d]: %s", min, max, err)
+		}
+
+		database, rows := page.Rows()
+		if err := fn(database, rows); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Rows returns the name of the database and the rows of the result.
+func (r *SearchesResult) Rows() (string, []map[string]interface{}) {
+	database, values := "events", r.Events
+	if r.Flows != nil {
+		database, values = "flows", r.Flows
+	}
+
+	rows := make([]map[string]interface{}, 0, len(values))
+	for _, value := range values {
+		if row, ok := value.(map[string]interface{}); ok {
+			rows = append(rows, row)
+		}
+	}
+
+	return database, rows
+}
+
+func hasErrorMessages(messages []ErrorMessages) bool {
+	for _, m := range messages {
+		if m.Severity == SearchStatusError {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,106 @@
+package goqradar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeArielServer serves a single search which completes after a few polls.
+func fakeArielServer(t *testing.T, rows int, key string) *httptest.Server {
+	polls := 0
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/ariel/searches"):
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(&Searches{SearchID: "s1", Status: SearchStatusWait})
+		case r.URL.Path == "/api/ariel/searches/s1":
+			polls++
+			status := SearchStatusExecute
+			if polls > 2 {
+				status = SearchStatusCompleted
+			}
+			json.NewEncoder(w).Encode(&Searches{SearchID: "s1", Status: status, RecordCount: rows})
+		case r.URL.Path == "/api/ariel/searches/s1/metadata":
+			json.NewEncoder(w).Encode(map[string]interface{}{"columns": []Columns{{Name: "id"}}})
+		case r.URL.Path == "/api/ariel/searches/s1/results":
+			var min, max int
+			fmt.Sscanf(r.Header.Get("Range"), "items=%d-%d", &min, &max)
+			page := []map[string]interface{}{}
+			for i := min; i <= max && i < rows; i++ {
+				page = append(page, map[string]interface{}{"id": i})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{key: page})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestRun(t *testing.T) {
+	server := fakeArielServer(t, 25, "flows")
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	result, err := client.Ariel.Run(context.Background(), "SELECT * FROM flows", &RunOptions{PollInterval: time.Millisecond, PageSize: 10})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	if result.Database != "flows" {
+		t.Fatalf("unexpected database: %s", result.Database)
+	}
+	if len(result.Rows) != 25 {
+		t.Fatalf("should have 25 rows, got %d", len(result.Rows))
+	}
+}
+
+func TestRunSearchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&Searches{
+			SearchID:      "s1",
+			Status:        SearchStatusError,
+			ErrorMessages: []ErrorMessages{{Code: "1", Message: "invalid query", Severity: "ERROR"}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	_, err := client.Ariel.Run(context.Background(), "SELECT", &RunOptions{PollInterval: time.Millisecond})
+	if _, ok := err.(*SearchError); !ok {
+		t.Fatalf("should be a search error, got: %v", err)
+	}
+}
+
+func TestRunCancelsSearch(t *testing.T) {
+	canceled := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Query().Get("status") == SearchStatusCanceled {
+			canceled <- struct{}{}
+		}
+		json.NewEncoder(w).Encode(&Searches{SearchID: "s1", Status: SearchStatusExecute})
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	client := NewClient(nil, server.URL, "token")
+	_, err := client.Ariel.Run(ctx, "SELECT * FROM events", &RunOptions{PollInterval: time.Millisecond})
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	select {
+	case <-canceled:
+	default:
+		t.Fatal("search should be canceled")
+	}
+}
@@ -0,0 +1,185 @@
+package goqradar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const savedSearchPageSize = 100
+
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// FindSavedSearch returns the saved search with the given name.
+func (endpoint *Endpoint) FindSavedSearch(ctx context.Context, name string) (*SavedSearch, error) {
+	response, err := endpoint.ListSavedSearch(ctx, "", "name = "+quoteFilterValue(name), 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing the saved searches: %s", err)
+	}
+
+	if len(response.SavedSearch) == 0 {
+		return nil, fmt.Errorf("saved search %q not found", name)
+	}
+
+	return response.SavedSearch[0], nil
+}
+
+// RunSavedSearch runs the saved search with the given name and fetches all the results.
+func (endpoint *Endpoint) RunSavedSearch(ctx context.Context, name string, opts *RunOptions) (*ArielResult, error) {
+	savedSearch, err := endpoint.FindSavedSearch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Submit the saved search
+	search, err := endpoint.PostSearches(ctx, "", savedSearch.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the search: %s", err)
+	}
+
+	return endpoint.runSearch(ctx, search.SearchID, opts)
+}
+
+// ExportSavedSearches writes the saved searches matching the filter into the
+// directory, one JSON file per saved search. It returns the written files.
+func (endpoint *Endpoint) ExportSavedSearches(ctx context.Context, dir, filter string) ([]string, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the directory: %s", err)
+	}
+
+	files := []string{}
+	for min := 0; ; min += savedSearchPageSize {
+		response, err := endpoint.ListSavedSearch(ctx, "", filter, min, min+savedSearchPageSize-1)
+		if err != nil {
+			return files, fmt.Errorf("error while listing the saved searches: %s", err)
+		}
+
+		for _, savedSearch := range response.SavedSearch {
+			path := filepath.Join(dir, savedSearchFileName(savedSearch.ID, savedSearch.Name))
+			err := WriteSavedSearchFile(path, &SavedSearchDefinition{
+				Name:          savedSearch.Name,
+				Aql:           savedSearch.Aql,
+				Description:   savedSearch.Description,
+				IsDashboard:   savedSearch.IsDashboard,
+				IsQuickSearch: savedSearch.IsQuickSearch,
+				IsShared:      savedSearch.IsShared,
+			})
+			if err != nil {
+				return files, err
+			}
+			files = append(files, path)
+		}
+
+		if len(response.SavedSearch) == 0 || response.Max+1 >= response.Total {
+			break
+		}
+	}
+
+	return files, nil
+}
+
+// ImportSavedSearches reads the JSON files of the directory and creates the
+// saved searches, or updates them when a saved search with the same name exists.
+func (endpoint *Endpoint) ImportSavedSearches(ctx context.Context, dir string) ([]*SavedSearch, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error while listing the files: %s", err)
+	}
+
+	savedSearches := []*SavedSearch{}
+	for _, path := range paths {
+		definition, err := ReadSavedSearchFile(path)
+		if err != nil {
+			return savedSearches, err
+		}
+
+		// Find the existing saved search
+		response, err := endpoint.ListSavedSearch(ctx, "id", "name = "+quoteFilterValue(definition.Name), 0, 0)
+		if err != nil {
+			return savedSearches, fmt.Errorf("error while listing the saved searches: %s", err)
+		}
+
+		var savedSearch *SavedSearch
+		if len(response.SavedSearch) > 0 {
+			savedSearch, err = endpoint.UpdateSavedSearch(ctx, response.SavedSearch[0].ID, definition, "")
+		} else {
+			savedSearch, err = endpoint.CreateSavedSearch(ctx, definition, "")
+		}
+		if err != nil {
+			return savedSearches, fmt.Errorf("error while importing %s: %s", path, err)
+		}
+		savedSearches = append(savedSearches, savedSearch)
+	}
+
+	return savedSearches, nil
+}
+
+// WriteSavedSearchFile writes the saved search definition as indented JSON.
+func WriteSavedSearchFile(path string, definition *SavedSearchDefinition) error {
+	b, err := json.MarshalIndent(definition, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error while marshalling the saved search: %s", err)
+	}
+
+	err = ioutil.WriteFile(path, append(b, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("error while writing the saved search: %s", err)
+	}
+
+	return nil
+}
+
+// ReadSavedSearchFile reads a saved search definition.
+func ReadSavedSearchFile(path string) (*SavedSearchDefinition, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading the saved search: %s", err)
+	}
+
+	var definition *SavedSearchDefinition
+	err = json.Unmarshal(b, &definition)
+	if err != nil {
+		return nil, fmt.Errorf("error while unmarshalling the saved search %s: %s", path, err)
+	}
+
+	if definition == nil || definition.Name == "" || definition.Aql == "" {
+		return nil, fmt.Errorf("saved search %s must have a name and an AQL query", path)
+	}
+
+	return definition, nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// quoteFilterValue quotes a string value for a QRadar filter.
+func quoteFilterValue(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+
+	return `"` + value + `"`
+}
+
+// savedSearchFileName returns the file name of the saved search. It starts
+// with the ID, so that the names which are sanitized into the same string do
+// not overwrite each other.
+func savedSearchFileName(id int, name string) string {
+	name = strings.Trim(unsafeFileNameChars.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		return strconv.Itoa(id) + ".json"
+	}
+
+	return strconv.Itoa(id) + "_" + name + ".json"
+}
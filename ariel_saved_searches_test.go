@@ -0,0 +1,188 @@
+package goqradar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSavedSearchesServer serves the saved searches endpoints from memory.
+type fakeSavedSearchesServer struct {
+	mu            sync.Mutex
+	savedSearches map[int]*SavedSearch
+	nextID        int
+}
+
+func (s *fakeSavedSearchesServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/ariel/saved_searches")
+	if path == "" && r.Method == http.MethodGet {
+		savedSearches := []*SavedSearch{}
+		for _, savedSearch := range s.savedSearches {
+			// Only the filters on the name are supported
+			if filter := r.URL.Query().Get("filter"); strings.HasPrefix(filter, "name = ") && quoteFilterValue(savedSearch.Name) != strings.TrimPrefix(filter, "name = ") {
+				continue
+			}
+			savedSearches = append(savedSearches, savedSearch)
+		}
+		sort.Slice(savedSearches, func(i, j int) bool { return savedSearches[i].ID < savedSearches[j].ID })
+		if len(savedSearches) == 0 {
+			w.Header().Set("Content-Range", "items */0")
+		} else {
+			w.Header().Set("Content-Range", fmt.Sprintf("items 0-%d/%d", len(savedSearches)-1, len(savedSearches)))
+		}
+		json.NewEncoder(w).Encode(savedSearches)
+		return
+	}
+
+	var definition *SavedSearchDefinition
+	if r.Method == http.MethodPost {
+		if r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(415)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&definition); err != nil {
+			w.WriteHeader(422)
+			return
+		}
+	}
+
+	if path == "" && r.Method == http.MethodPost {
+		s.nextID++
+		savedSearch := &SavedSearch{ID: s.nextID}
+		setSavedSearchDefinition(savedSearch, definition)
+		s.savedSearches[savedSearch.ID] = savedSearch
+		w.WriteHeader(201)
+		json.NewEncoder(w).Encode(savedSearch)
+		return
+	}
+
+	id, _ := strconv.Atoi(strings.TrimPrefix(path, "/"))
+	savedSearch, ok := s.savedSearches[id]
+	if !ok {
+		w.WriteHeader(404)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		setSavedSearchDefinition(savedSearch, definition)
+		json.NewEncoder(w).Encode(savedSearch)
+	case http.MethodDelete:
+		delete(s.savedSearches, id)
+		w.WriteHeader(202)
+		json.NewEncoder(w).Encode(&SavedSearchDependentTask{ID: 7, Status: "QUEUED"})
+	}
+}
+
+func setSavedSearchDefinition(savedSearch *SavedSearch, definition *SavedSearchDefinition) {
+	savedSearch.Name = definition.Name
+	savedSearch.Aql = definition.Aql
+	savedSearch.Description = definition.Description
+	savedSearch.IsDashboard = definition.IsDashboard
+	savedSearch.IsQuickSearch = definition.IsQuickSearch
+	savedSearch.IsShared = definition.IsShared
+}
+
+func TestSavedSearchLifecycle(t *testing.T) {
+	server := httptest.NewServer(&fakeSavedSearchesServer{savedSearches: map[int]*SavedSearch{}})
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	ctx := context.Background()
+
+	created, err := client.Ariel.CreateSavedSearch(ctx, &SavedSearchDefinition{Name: "Top talkers", Aql: "SELECT sourceip FROM events"}, "")
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if created.ID != 1 || created.Name != "Top talkers" {
+		t.Fatalf("unexpected saved search: %+v", created)
+	}
+
+	updated, err := client.Ariel.UpdateSavedSearch(ctx, created.ID, &SavedSearchDefinition{Name: "Top talkers", Aql: "SELECT sourceip FROM flows", IsShared: true}, "")
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if updated.Aql != "SELECT sourceip FROM flows" || !updated.IsShared {
+		t.Fatalf("unexpected saved search: %+v", updated)
+	}
+
+	task, err := client.Ariel.DeleteSavedSearch(ctx, created.ID, "")
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if task.ID != 7 || task.Status != "QUEUED" {
+		t.Fatalf("unexpected task: %+v", task)
+	}
+
+	if _, err := client.Ariel.UpdateSavedSearch(ctx, created.ID, &SavedSearchDefinition{Name: "Top talkers", Aql: "SELECT 1 FROM events"}, ""); err == nil {
+		t.Fatal("should error with a deleted saved search")
+	}
+}
+
+func TestSavedSearchesExportImport(t *testing.T) {
+	source := &fakeSavedSearchesServer{savedSearches: map[int]*SavedSearch{
+		1: {ID: 1, Name: "a/b", Aql: "SELECT 1 FROM events", IsShared: true},
+		2: {ID: 2, Name: "a:b", Aql: "SELECT 2 FROM events"},
+		3: {ID: 3, Name: "???", Aql: "SELECT 3 FROM events", Description: "Symbols only"},
+	}, nextID: 3}
+	sourceServer := httptest.NewServer(source)
+	defer sourceServer.Close()
+
+	dir, err := ioutil.TempDir("", "goqradar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Export
+	ctx := context.Background()
+	files, err := NewClient(nil, sourceServer.URL, "token").Ariel.ExportSavedSearches(ctx, dir, "")
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	names := []string{}
+	for _, file := range files {
+		names = append(names, filepath.Base(file))
+	}
+	if strings.Join(names, ",") != "1_a_b.json,2_a_b.json,3.json" {
+		t.Fatalf("unexpected files: %v", names)
+	}
+
+	// Import into a console which already has one of them
+	destination := &fakeSavedSearchesServer{savedSearches: map[int]*SavedSearch{
+		10: {ID: 10, Name: "a:b", Aql: "SELECT 0 FROM events"},
+	}, nextID: 10}
+	destinationServer := httptest.NewServer(destination)
+	defer destinationServer.Close()
+
+	savedSearches, err := NewClient(nil, destinationServer.URL, "token").Ariel.ImportSavedSearches(ctx, dir)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if len(savedSearches) != 3 || len(destination.savedSearches) != 3 {
+		t.Fatalf("unexpected saved searches: %d imported, %d in total", len(savedSearches), len(destination.savedSearches))
+	}
+	if destination.savedSearches[10].Aql != "SELECT 2 FROM events" {
+		t.Fatalf("the existing saved search should be updated: %+v", destination.savedSearches[10])
+	}
+	for _, savedSearch := range destination.savedSearches {
+		if savedSearch.Name == "a/b" && (!savedSearch.IsShared || savedSearch.Aql != "SELECT 1 FROM events") {
+			t.Fatalf("unexpected saved search: %+v", savedSearch)
+		}
+		if savedSearch.Name == "???" && savedSearch.Description != "Symbols only" {
+			t.Fatalf("unexpected saved search: %+v", savedSearch)
+		}
+	}
+}
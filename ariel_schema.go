@@ -0,0 +1,113 @@
+package goqradar
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const databaseColumnsPageSize = 500
+
+// Ariel databases.
+const (
+	DatabaseEvents = "events"
+	DatabaseFlows  = "flows"
+)
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// GetDatabaseSchema retrieves all the columns of the given Ariel database.
+func (endpoint *Endpoint) GetDatabaseSchema(ctx context.Context, databaseName string) (*Database, error) {
+	schema := &Database{Name: databaseName}
+
+	for min := 0; ; min += databaseColumnsPageSize {
+		page, err := endpoint.GetDatabase(ctx, databaseName, "", "", min, min+databaseColumnsPageSize-1)
+		if err != nil {
+			return nil, fmt.Errorf("error while retrieving the columns of %s: %s", databaseName, err)
+		}
+
+		schema.Columns = append(schema.Columns, page.Columns...)
+		schema.Total = page.Total
+
+		if len(page.Columns) == 0 || page.Max+1 >= page.Total {
+			break
+		}
+	}
+	schema.Max = len(schema.Columns) - 1
+
+	return schema, nil
+}
+
+// ListDatabaseSchemas retrieves the columns of every Ariel database, by database name.
+func (endpoint *Endpoint) ListDatabaseSchemas(ctx context.Context) (map[string]*Database, error) {
+	databases, err := endpoint.ListDatabase(ctx, "", 0, 49)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing the databases: %s", err)
+	}
+
+	schemas := map[string]*Database{}
+	for _, name := range databases.Databases {
+		schema, err := endpoint.GetDatabaseSchema(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		schemas[name] = schema
+	}
+
+	return schemas, nil
+}
+
+// Column returns the column with the given name. The lookup is case insensitive,
+// as AQL column names are.
+func (d *Database) Column(name string) (*Columns, bool) {
+	for i := range d.Columns {
+		if strings.EqualFold(d.Columns[i].Name, name) {
+			return &d.Columns[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// HasColumn returns true if the database has the given column.
+func (d *Database) HasColumn(name string) bool {
+	_, ok := d.Column(name)
+
+	return ok
+}
+
+// IsIndexable returns true if the given column exists and is indexable.
+func (d *Database) IsIndexable(name string) bool {
+	column, ok := d.Column(name)
+
+	return ok && column.Indexable
+}
+
+// IsNullable returns true if the given column exists and is nullable.
+func (d *Database) IsNullable(name string) bool {
+	column, ok := d.Column(name)
+
+	return ok && column.Nullable
+}
+
+// ColumnNames returns the names of the columns.
+func (d *Database) ColumnNames() []string {
+	names := make([]string, 0, len(d.Columns))
+	for _, column := range d.Columns {
+		names = append(names, column.Name)
+	}
+
+	return names
+}
+
+// ColumnNames returns the names of the columns, in the order of the results.
+func (m *SearchMetadata) ColumnNames() []string {
+	names := make([]string, 0, len(m.Columns))
+	for _, column := range m.Columns {
+		names = append(names, column.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,359 @@
+package goqradar
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultSliceConcurrency = 3
+
+// Slice statuses reported to the progress function.
+const (
+	SliceStarted   = "STARTED"
+	SliceCompleted = "COMPLETED"
+	SliceFailed    = "FAILED"
+)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// SliceOptions are the options of a time-sliced Ariel query run.
+type SliceOptions struct {
+	// RunOptions are the options of the run of every slice.
+	RunOptions *RunOptions
+
+	// Slices is the number of slices the START/STOP window is split into.
+	Slices int
+
+	// Concurrency is the maximum number of concurrent searches. Default is 3.
+	Concurrency int
+
+	// Progress is called when a slice starts, completes or fails. The calls
+	// are serialized.
+	Progress func(SliceProgress)
+}
+
+// SliceProgress is the progress of a slice.
+type SliceProgress struct {
+	Index    int
+	Total    int
+	Start    time.Time
+	Stop     time.Time
+	Status   string
+	SearchID string
+	Rows     int
+	Err      error
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// RunSliced splits the START/STOP window of the query into slices, runs them
+// concurrently and merges the results. ORDER BY and LIMIT are re-applied on
+// the merged rows, and the COUNT, SUM, MIN and MAX aggregates are re-aggregated.
+// The aggregates must be aliased.
+func (endpoint *Endpoint) RunSliced(ctx context.Context, query *AQLQuery, opts *SliceOptions) (*ArielResult, error) {
+	if opts == nil || opts.Slices <= 0 {
+		return nil, fmt.Errorf("the number of slices must be positive")
+	}
+	if query.start.IsZero() || query.stop.IsZero() {
+		return nil, fmt.Errorf("the query must have a START/STOP window")
+	}
+	if _, err := query.Build(); err != nil {
+		return nil, err
+	}
+	if err := checkSliceable(query); err != nil {
+		return nil, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultSliceConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Build the slices
+	slices := sliceQuery(query, opts.Slices)
+	results := make([]*ArielResult, len(slices))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	progress := func(p SliceProgress) {
+		if opts.Progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		opts.Progress(p)
+	}
+
+	semaphore := make(chan struct{}, concurrency)
+	for i, slice := range slices {
+		wg.Add(1)
+		go func(i int, slice *AQLQuery) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+
+			p := SliceProgress{Index: i, Total: len(slices), Start: slice.start, Stop: slice.stop, Status: SliceStarted}
+			progress(p)
+
+			aql, err := slice.Build()
+			if err == nil {
+				results[i], err = endpoint.Run(ctx, aql, opts.RunOptions)
+			}
+
+			if err != nil {
+				p.Status, p.Err = SliceFailed, err
+				progress(p)
+
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("error while running the slice %d: %s", i, err)
+				}
+				mu.Unlock()
+				cancel()
+				return
+			}
+
+			p.Status, p.SearchID, p.Rows = SliceCompleted, results[i].SearchID, len(results[i].Rows)
+			progress(p)
+		}(i, slice)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return mergeSlices(query, results), nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// checkSliceable returns an error if the results of the slices cannot be merged.
+func checkSliceable(query *AQLQuery) error {
+	for _, column := range query.columns {
+		switch column.aggregate {
+		case "":
+		case "COUNT", "SUM", "MIN", "MAX":
+			if column.alias == "" {
+				return fmt.Errorf("the aggregate %s must be aliased to be merged", column.expr)
+			}
+		default:
+			return fmt.Errorf("the aggregate %s cannot be merged across slices", column.aggregate)
+		}
+	}
+
+	return nil
+}
+
+// sliceQuery splits the window of the query into contiguous slices. As the
+// STOP time is excluded, the STOP of a slice is the START of the next one.
+func sliceQuery(query *AQLQuery, n int) []*AQLQuery {
+	window := query.stop.Sub(query.start)
+	step := window / time.Duration(n)
+	if step < time.Millisecond {
+		// The times are in milliseconds, a shorter window is a single slice
+		step = time.Millisecond
+		n = int((window + time.Millisecond - 1) / time.Millisecond)
+		if n < 1 {
+			n = 1
+		}
+	}
+
+	// The LIMIT of an aggregated query is applied after the merge
+	aggregated := isAggregated(query)
+
+	slices := make([]*AQLQuery, 0, n)
+	for i := 0; i < n; i++ {
+		slice := query.Clone()
+		slice.start = query.start.Add(time.Duration(i) * step)
+		slice.stop = slice.start.Add(step)
+		if i == n-1 {
+			slice.stop = query.stop
+		}
+		if aggregated {
+			slice.limit = 0
+		}
+		slices = append(slices, slice)
+	}
+
+	return slices
+}
+
+func isAggregated(query *AQLQuery) bool {
+	for _, column := range query.columns {
+		if column.aggregate != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeSlices merges the results of the slices.
+func mergeSlices(query *AQLQuery, results []*ArielResult) *ArielResult {
+	merged := &ArielResult{Database: query.database, Rows: []map[string]interface{}{}}
+	for _, result := range results {
+		merged.Rows = append(merged.Rows, result.Rows...)
+	}
+
+	if isAggregated(query) {
+		merged.Rows = reaggregate(query, merged.Rows)
+	}
+
+	if len(query.orderBy) > 0 {
+		sort.SliceStable(merged.Rows, func(i, j int) bool {
+			for _, order := range query.orderBy {
+				name := order.expr.name()
+				c := compareValues(merged.Rows[i][name], merged.Rows[j][name])
+				if c == 0 {
+					continue
+				}
+				if order.desc {
+					return c > 0
+				}
+				return c < 0
+			}
+			return false
+		})
+	}
+
+	if query.limit > 0 && len(merged.Rows) > query.limit {
+		merged.Rows = merged.Rows[:query.limit]
+	}
+
+	return merged
+}
+
+// reaggregate merges the rows of the same group.
+func reaggregate(query *AQLQuery, rows []map[string]interface{}) []map[string]interface{} {
+	groups := map[string]map[string]interface{}{}
+	keys := []string{}
+
+	for _, row := range rows {
+		// Build the key of the group
+		parts := []string{}
+		for _, column := range query.columns {
+			if column.aggregate == "" {
+				parts = append(parts, fmt.Sprintf("%v", row[column.name()]))
+			}
+		}
+		key := strings.Join(parts, "\x00")
+
+		group, ok := groups[key]
+		if !ok {
+			group = map[string]interface{}{}
+			for k, v := range row {
+				group[k] = v
+			}
+			groups[key] = group
+			keys = append(keys, key)
+			continue
+		}
+
+		// Merge the aggregates, a slice without rows in the group has no value
+		for _, column := range query.columns {
+			name := column.name()
+			if column.aggregate == "" {
+				continue
+			}
+			value, err := toFloat(row[name])
+			if row[name] == nil || err != nil {
+				continue
+			}
+			current, err := toFloat(group[name])
+			if group[name] == nil || err != nil {
+				group[name] = row[name]
+				continue
+			}
+
+			switch column.aggregate {
+			case "COUNT", "SUM":
+				group[name] = addAggregates(group[name], row[name], current, value)
+			case "MIN":
+				if value < current {
+					group[name] = row[name]
+				}
+			case "MAX":
+				if value > current {
+					group[name] = row[name]
+				}
+			}
+		}
+	}
+
+	merged := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		merged = append(merged, groups[key])
+	}
+
+	return merged
+}
+
+// addAggregates adds two values of a COUNT or SUM, and keeps the type of the
+// integers.
+func addAggregates(a, b interface{}, fa, fb float64) interface{} {
+	switch x := a.(type) {
+	case int:
+		if y, ok := b.(int); ok {
+			return x + y
+		}
+	case int64:
+		if y, ok := b.(int64); ok {
+			return x + y
+		}
+	}
+
+	return fa + fb
+}
+
+// compareValues compares two values of the results. Numbers are compared
+// numerically, other values as strings, and nil values come first.
+func compareValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+
+	fa, errA := toFloat(a)
+	fb, errB := toFloat(b)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
@@ -0,0 +1,100 @@
+package goqradar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSliceQuery(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	query := Select(Col("sourceip"), Count(All()).As("total")).GroupBy(Col("sourceip")).Limit(5).Between(start, start.Add(time.Hour))
+
+	slices := sliceQuery(query, 4)
+	if len(slices) != 4 {
+		t.Fatalf("should have 4 slices, got %d", len(slices))
+	}
+	if !slices[0].start.Equal(start) || !slices[3].stop.Equal(start.Add(time.Hour)) {
+		t.Fatal("slices should cover the window")
+	}
+	if !slices[1].start.Equal(slices[0].stop) {
+		t.Fatal("slices should be contiguous")
+	}
+	if slices[0].limit != 0 {
+		t.Fatal("aggregated slices should not be limited")
+	}
+}
+
+func TestSliceQueryShortWindow(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+
+	slices := sliceQuery(Select(Col("sourceip")).Between(start, start.Add(500*time.Microsecond)), 4)
+	if len(slices) != 1 || !slices[0].start.Equal(start) || !slices[0].stop.Equal(start.Add(500*time.Microsecond)) {
+		t.Fatalf("should have a single slice covering the window, got %d", len(slices))
+	}
+
+	slices = sliceQuery(Select(Col("sourceip")).Between(start, start.Add(2500*time.Microsecond)), 10)
+	if len(slices) != 3 || !slices[2].stop.Equal(start.Add(2500*time.Microsecond)) {
+		t.Fatalf("should have 3 slices covering the window, got %d", len(slices))
+	}
+}
+
+func TestMergeSlices(t *testing.T) {
+	query := Select(Col("sourceip"), Count(All()).As("total"), Func("MAX", Col("magnitude")).As("max")).
+		GroupBy(Col("sourceip")).
+		OrderByDesc(Col("total")).
+		Limit(2)
+
+	results := []*ArielResult{
+		{Rows: []map[string]interface{}{
+			{"sourceip": "10.0.0.1", "total": float64(3), "max": float64(2)},
+			{"sourceip": "10.0.0.2", "total": float64(1), "max": float64(9)},
+		}},
+		{Rows: []map[string]interface{}{
+			{"sourceip": "10.0.0.2", "total": float64(5), "max": float64(4)},
+			{"sourceip": "10.0.0.3", "total": float64(2), "max": float64(1)},
+		}},
+	}
+
+	merged := mergeSlices(query, results)
+	if len(merged.Rows) != 2 {
+		t.Fatalf("should have 2 rows, got %d", len(merged.Rows))
+	}
+	if merged.Rows[0]["sourceip"] != "10.0.0.2" || merged.Rows[0]["total"] != float64(6) || merged.Rows[0]["max"] != float64(9) {
+		t.Fatalf("unexpected first row: %v", merged.Rows[0])
+	}
+	if merged.Rows[1]["sourceip"] != "10.0.0.1" {
+		t.Fatalf("unexpected second row: %v", merged.Rows[1])
+	}
+}
+
+func TestMergeSlicesMissingValues(t *testing.T) {
+	query := Select(Col("sourceip"), Count(All()).As("total"), Func("MIN", Col("magnitude")).As("min")).
+		GroupBy(Col("sourceip"))
+
+	results := []*ArielResult{
+		{Rows: []map[string]interface{}{{"sourceip": "10.0.0.1", "total": 0, "min": nil}}},
+		{Rows: []map[string]interface{}{{"sourceip": "10.0.0.1", "total": 3, "min": float64(4)}}},
+		{Rows: []map[string]interface{}{{"sourceip": "10.0.0.1", "total": 2, "min": "n/a"}}},
+		{Rows: []map[string]interface{}{{"sourceip": "10.0.0.1", "total": 1, "min": float64(6)}}},
+	}
+
+	merged := mergeSlices(query, results)
+	if len(merged.Rows) != 1 {
+		t.Fatalf("should have 1 row, got %d", len(merged.Rows))
+	}
+	if merged.Rows[0]["min"] != float64(4) {
+		t.Fatalf("the missing values should be skipped: %v", merged.Rows[0])
+	}
+	if total, ok := merged.Rows[0]["total"].(int); !ok || total != 6 {
+		t.Fatalf("the integers should be kept: %#v", merged.Rows[0]["total"])
+	}
+}
+
+func TestCheckSliceable(t *testing.T) {
+	if err := checkSliceable(Select(Func("AVG", Col("magnitude")).As("avg"))); err == nil {
+		t.Fatal("AVG should not be sliceable")
+	}
+	if err := checkSliceable(Select(Count(All()))); err == nil {
+		t.Fatal("unaliased aggregates should not be sliceable")
+	}
+}
@@ -0,0 +1,280 @@
+package goqradar
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	defaultTailInterval  = 30 * time.Second
+	defaultTailLag       = time.Minute
+	defaultTailMaxWindow = time.Hour
+)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// TailOptions are the options of an Ariel tail.
+type TailOptions struct {
+	// Interval is the time between two queries. Default is 30 seconds.
+	Interval time.Duration
+
+	// Lag is how far behind the current time the windows stop, so that the
+	// events still being ingested by the console are not missed. Default is 1 minute.
+	Lag time.Duration
+
+	// MaxWindow is the maximum duration of a window. After a downtime, the
+	// tail catches up with several windows. Default is 1 hour.
+	MaxWindow time.Duration
+
+	// Since is the start of the first window, when there is no checkpoint.
+	// Default is the current time minus the lag.
+	Since time.Time
+
+	// CheckpointFile is the file where the position and the rows seen at the
+	// position are saved after every window. It is optional.
+	CheckpointFile string
+
+	// RunOptions are the options of the run of every window.
+	RunOptions *RunOptions
+}
+
+// ArielTail repeatedly runs an AQL query over the window since the last seen
+// starttime and emits the new rows. The next window starts at the last seen
+// starttime, so that the events ingested late are still emitted, and the rows
+// already emitted at this starttime are skipped.
+type ArielTail struct {
+	ariel Ariel
+	query *AQLQuery
+	opts  TailOptions
+	now   func() time.Time
+
+	position time.Time
+	boundary map[string]bool
+}
+
+type tailCheckpoint struct {
+	Position int64    `json:"position"`
+	Boundary []string `json:"boundary"`
+}
+
+//------------------------------------------------------------------------------
+// Factory
+//------------------------------------------------------------------------------
+
+// NewArielTail returns a new tail of the query, which must not have a time
+// clause. The starttime column is added to the query if it is not selected.
+func NewArielTail(ariel Ariel, query *AQLQuery, opts *TailOptions) (*ArielTail, error) {
+	if !query.start.IsZero() || query.last > 0 {
+		return nil, fmt.Errorf("the query of a tail must not have a time clause")
+	}
+	if opts == nil {
+		opts = &TailOptions{}
+	}
+
+	t := &ArielTail{
+		ariel:    ariel,
+		query:    query.Clone(),
+		opts:     *opts,
+		now:      time.Now,
+		boundary: map[string]bool{},
+	}
+	if t.opts.Interval <= 0 {
+		t.opts.Interval = defaultTailInterval
+	}
+	if t.opts.Lag <= 0 {
+		t.opts.Lag = defaultTailLag
+	}
+	if t.opts.MaxWindow <= 0 {
+		t.opts.MaxWindow = defaultTailMaxWindow
+	}
+
+	// Select the starttime
+	hasStartTime := false
+	for _, column := range t.query.columns {
+		if column.expr == "*" || column.name() == "starttime" {
+			hasStartTime = true
+		}
+	}
+	if !hasStartTime {
+		t.query.columns = append(t.query.columns, Col("starttime"))
+	}
+
+	// Restore the position
+	t.position = t.opts.Since
+	if t.opts.CheckpointFile != "" {
+		if err := t.loadCheckpoint(); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// Position returns the start of the next window.
+func (t *ArielTail) Position() time.Time {
+	return t.position
+}
+
+// Run queries the windows until the context is canceled, and sends the new
+// rows on the channel. It does not close the channel.
+func (t *ArielTail) Run(ctx context.Context, rows chan<- map[string]interface{}) error {
+	for {
+		caughtUp, err := t.poll(ctx, rows)
+		if err != nil {
+			return err
+		}
+
+		// Catch up without waiting
+		if !caughtUp {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(t.opts.Interval):
+		}
+	}
+}
+
+// poll queries the next window. It returns true if the window reached the current time.
+func (t *ArielTail) poll(ctx context.Context, rows chan<- map[string]interface{}) (bool, error) {
+	stop := t.now().Add(-t.opts.Lag)
+	if t.position.IsZero() {
+		t.position = stop.Add(-t.opts.Interval)
+	}
+	if !stop.After(t.position) {
+		return true, nil
+	}
+
+	// Handle the gaps with several windows
+	caughtUp := true
+	if stop.Sub(t.position) > t.opts.MaxWindow {
+		stop = t.position.Add(t.opts.MaxWindow)
+		caughtUp = false
+	}
+
+	// Run the window
+	aql, err := t.query.Clone().Between(t.position, stop).Build()
+	if err != nil {
+		return false, err
+	}
+	result, err := t.ariel.Run(ctx, aql, t.opts.RunOptions)
+	if err != nil {
+		return false, fmt.Errorf("error while running the window: %s", err)
+	}
+
+	// Emit the new rows, skipping the ones already emitted at the position
+	last := epochMillis(t.position)
+	for _, row := range result.Rows {
+		if starttime, ok := rowStartTime(row); ok && starttime > last {
+			last = starttime
+		}
+		if t.boundary[hashRow(row)] {
+			continue
+		}
+
+		select {
+		case rows <- row:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+
+	// Move to the last seen starttime. A truncated window has been entirely
+	// read, so the next one starts at its STOP time.
+	if !caughtUp && last < epochMillis(stop) {
+		last = epochMillis(stop)
+	}
+	boundary := map[string]bool{}
+	if last == epochMillis(t.position) {
+		boundary = t.boundary
+	}
+	for _, row := range result.Rows {
+		if starttime, ok := rowStartTime(row); ok && starttime == last {
+			boundary[hashRow(row)] = true
+		}
+	}
+	t.position = time.Unix(0, last*int64(time.Millisecond))
+	t.boundary = boundary
+	if t.opts.CheckpointFile != "" {
+		if err := t.saveCheckpoint(); err != nil {
+			return false, err
+		}
+	}
+
+	return caughtUp, nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+func (t *ArielTail) loadCheckpoint() error {
+	b, err := ioutil.ReadFile(t.opts.CheckpointFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error while reading the checkpoint: %s", err)
+	}
+
+	var checkpoint tailCheckpoint
+	err = json.Unmarshal(b, &checkpoint)
+	if err != nil {
+		return fmt.Errorf("error while unmarshalling the checkpoint: %s", err)
+	}
+
+	t.position = time.Unix(0, checkpoint.Position*int64(time.Millisecond))
+	for _, hash := range checkpoint.Boundary {
+		t.boundary[hash] = true
+	}
+
+	return nil
+}
+
+func (t *ArielTail) saveCheckpoint() error {
+	checkpoint := tailCheckpoint{Position: epochMillis(t.position), Boundary: []string{}}
+	for hash := range t.boundary {
+		checkpoint.Boundary = append(checkpoint.Boundary, hash)
+	}
+	sort.Strings(checkpoint.Boundary)
+
+	if err := writeJSONFile(t.opts.CheckpointFile, checkpoint); err != nil {
+		return fmt.Errorf("error while writing the checkpoint: %s", err)
+	}
+
+	return nil
+}
+
+// rowStartTime returns the starttime of the row, in milliseconds.
+func rowStartTime(row map[string]interface{}) (int64, bool) {
+	starttime, err := toInteger(row["starttime"])
+	if err != nil {
+		return 0, false
+	}
+
+	return int64(starttime), true
+}
+
+// hashRow returns a stable hash of the row.
+func hashRow(row map[string]interface{}) string {
+	// The keys of the maps are sorted by the encoder
+	b, _ := json.Marshal(row)
+	sum := sha1.Sum(b)
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package goqradar
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubAriel overrides Run of the Ariel endpoint.
+type stubAriel struct {
+	Ariel
+	run func(aql string) (*ArielResult, error)
+}
+
+func (s *stubAriel) Run(ctx context.Context, aql string, opts *RunOptions) (*ArielResult, error) {
+	return s.run(aql)
+}
+
+func TestArielTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goqradar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := time.Unix(1600000000, 0)
+	ms := func(d time.Duration) float64 { return float64(epochMillis(base.Add(d))) }
+	windows := [][]map[string]interface{}{
+		// The row b lands exactly on the STOP time of the first window
+		{{"id": "a", "starttime": ms(-3*time.Hour + 10*time.Millisecond)}, {"id": "b", "starttime": ms(-2 * time.Hour)}},
+		{{"id": "b", "starttime": ms(-2 * time.Hour)}, {"id": "c", "starttime": ms(-2*time.Hour + 5*time.Millisecond)}},
+		{{"id": "d", "starttime": ms(-30 * time.Minute)}},
+		// The row e is ingested after the previous window was queried
+		{{"id": "d", "starttime": ms(-30 * time.Minute)}, {"id": "e", "starttime": ms(-25 * time.Minute)}, {"id": "f", "starttime": ms(-20 * time.Minute)}},
+	}
+	queries := []string{}
+	ariel := &stubAriel{run: func(aql string) (*ArielResult, error) {
+		queries = append(queries, aql)
+		rows := windows[0]
+		windows = windows[1:]
+		return &ArielResult{Rows: rows}, nil
+	}}
+
+	opts := &TailOptions{
+		Lag:            time.Minute,
+		MaxWindow:      time.Hour,
+		Since:          base.Add(-3 * time.Hour),
+		CheckpointFile: filepath.Join(dir, "tail.json"),
+	}
+	newTail := func() *ArielTail {
+		tail, err := NewArielTail(ariel, Select(Col("id")), opts)
+		if err != nil {
+			t.Fatalf("should not error but error is: %s", err)
+		}
+		tail.now = func() time.Time { return base.Add(time.Minute) }
+		return tail
+	}
+	rows := make(chan map[string]interface{}, 10)
+
+	// The gap is caught up with windows of one hour
+	caughtUp, err := newTail().poll(context.Background(), rows)
+	if err != nil || caughtUp {
+		t.Fatalf("first window should not catch up: %v", err)
+	}
+
+	// Restart from the checkpoint, the row b is skipped
+	tail := newTail()
+	if !tail.Position().Equal(base.Add(-2*time.Hour)) || len(tail.boundary) != 1 {
+		t.Fatalf("position should be restored: %s %v", tail.Position(), tail.boundary)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := tail.poll(context.Background(), rows); err != nil {
+			t.Fatalf("should not error but error is: %s", err)
+		}
+	}
+	close(rows)
+
+	ids := ""
+	for row := range rows {
+		ids += row["id"].(string)
+	}
+	if ids != "abcdef" {
+		t.Fatalf("every row should be emitted once, got %s", ids)
+	}
+
+	// The truncated windows are contiguous, then the windows start at the last seen starttime
+	starts := []time.Duration{-3 * time.Hour, -2 * time.Hour, -time.Hour, -30 * time.Minute}
+	for i, start := range starts {
+		if !strings.Contains(queries[i], "START "+strconv.FormatInt(int64(ms(start)), 10)+" ") {
+			t.Fatalf("unexpected window %d: %s", i, queries[i])
+		}
+	}
+	if !tail.Position().Equal(base.Add(-20 * time.Minute)) {
+		t.Fatalf("position should be the last seen starttime: %s", tail.Position())
+	}
+}
@@ -0,0 +1,104 @@
+package goqradar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetDatabase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/ariel/databases/events" {
+			w.WriteHeader(404)
+			return
+		}
+		if r.Header.Get("Range") == "items=0-1" {
+			w.Header().Set("Content-Range", "items 0-1/3")
+		}
+		w.Write([]byte(`{"columns":[{"name":"sourceip","indexable":true,"nullable":false,"argument_type":"IP"},{"name":"username","indexable":true,"nullable":true}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+
+	// Paginated columns
+	database, err := client.Ariel.GetDatabase(context.Background(), "events", "", "", 0, 1)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if database.Name != "events" || database.Total != 3 || database.Max != 1 {
+		t.Fatalf("unexpected database: %+v", database)
+	}
+	if len(database.Columns) != 2 || database.Columns[0].Name != "sourceip" || database.Columns[0].ArgumentType != "IP" {
+		t.Fatalf("unexpected columns: %+v", database.Columns)
+	}
+	if !database.IsNullable("username") || database.IsNullable("sourceip") {
+		t.Fatal("only the username should be nullable")
+	}
+
+	// All the columns, without Content-Range
+	database, err = client.Ariel.GetDatabase(context.Background(), "events", "", "", 0, 49)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if database.Total != 2 || database.Max != 1 {
+		t.Fatalf("unexpected database: %+v", database)
+	}
+}
+
+func TestGetSearchMetadata(t *testing.T) {
+	bodies := map[string]string{
+		"root":   `{"columns":[{"name":"sourceip"},{"name":"Event Count"}]}`,
+		"nested": `{"events":{"columns":[{"name":"sourceip"},{"name":"Event Count"}]}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/ariel/searches/"), "/metadata")
+		w.Write([]byte(bodies[id]))
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	for id := range bodies {
+		metadata, err := client.Ariel.GetSearchMetadata(context.Background(), id)
+		if err != nil {
+			t.Fatalf("%s: should not error but error is: %s", id, err)
+		}
+		if strings.Join(metadata.ColumnNames(), ",") != "sourceip,Event Count" {
+			t.Fatalf("%s: unexpected columns: %v", id, metadata.ColumnNames())
+		}
+	}
+}
+
+func TestSearchesCloseBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			w.WriteHeader(201)
+			w.Write([]byte(`{"search_id":"abc","status":"WAIT"}`))
+		case strings.HasSuffix(r.URL.Path, "/results"):
+			w.Write([]byte(`{"events":[]}`))
+		default:
+			w.Write([]byte(`{"search_id":"abc","status":"COMPLETED"}`))
+		}
+	}))
+	defer server.Close()
+
+	tracker := &bodyTracker{}
+	client := NewClient(&http.Client{Transport: tracker}, server.URL, "token")
+	ctx := context.Background()
+
+	if _, err := client.Ariel.PostSearches(ctx, "SELECT * FROM events", 0); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if _, err := client.Ariel.GetSearchesID(ctx, "abc", ""); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if _, err := client.Ariel.GetSearchesResults(ctx, "abc", 0, 49); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if n := tracker.unclosed(); n != 0 {
+		t.Fatalf("%d response bodies are not closed", n)
+	}
+}
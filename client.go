@@ -25,6 +25,18 @@ type Client struct {
 	// Version is the API version.
 	Version string
 
+	// Tracer is the optional tracer used to create a span per request.
+	Tracer Tracer
+
+	// Logger is the optional logger used to log every request at debug level.
+	Logger Logger
+
+	// LogBodies enables the logging of the request and response bodies.
+	LogBodies bool
+
+	// MaxLogBodySize is the size after which the logged bodies are truncated.
+	MaxLogBodySize int
+
 	// Endpoints
 	Access             Access
 	Analytics          Analytics
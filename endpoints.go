@@ -2,6 +2,7 @@ package goqradar
 
 import (
 	"context"
+	"io"
 )
 
 //------------------------------------------------------------------------------
@@ -31,13 +32,33 @@ type Analytics interface {
 type Ariel interface {
 	GetSavedSearch(context.Context, int, string) (*SavedSearch, error)
 	ListSavedSearch(context.Context, string, string, int, int) (*SavedSearchPaginatedResponse, error)
+	CreateSavedSearch(context.Context, *SavedSearchDefinition, string) (*SavedSearch, error)
+	UpdateSavedSearch(context.Context, int, *SavedSearchDefinition, string) (*SavedSearch, error)
+	DeleteSavedSearch(context.Context, int, string) (*SavedSearchDependentTask, error)
 	GetSavedSearchDependentTask(context.Context, int, string) (*SavedSearchDependentTask, error)
+	GetSavedSearchDeleteTask(context.Context, int, string) (*SavedSearchDependentTask, error)
+	FindSavedSearch(context.Context, string) (*SavedSearch, error)
+	RunSavedSearch(context.Context, string, *RunOptions) (*ArielResult, error)
+	ExportSavedSearches(context.Context, string, string) ([]string, error)
+	ImportSavedSearches(context.Context, string) ([]*SavedSearch, error)
 	GetSearchesID(context.Context, string, string) (*Searches, error)
 	ListSearches(context.Context, string, string, int, int) (*SearchesPaginatedResponse, error)
 	GetDatabase(context.Context, string, string, string, int, int) (*Database, error)
 	ListDatabase(context.Context, string, int, int) (*DatabasePaginatedResponse, error)
+	GetDatabaseSchema(context.Context, string) (*Database, error)
+	ListDatabaseSchemas(context.Context) (map[string]*Database, error)
+	GetSearchMetadata(context.Context, string) (*SearchMetadata, error)
 	GetSearchesResults(context.Context, string, int, int) (*SearchesResult, error)
+	GetSearchesResultsRaw(context.Context, string, string, int, int) (io.ReadCloser, error)
 	PostSearches(context.Context, string, int) (*Searches, error)
+	UpdateSearch(context.Context, string, *SearchUpdate) (*Searches, error)
+	CancelSearch(context.Context, string) (*Searches, error)
+	DeleteSearch(context.Context, string) (*Searches, error)
+	Run(context.Context, string, *RunOptions) (*ArielResult, error)
+	RunSliced(context.Context, *AQLQuery, *SliceOptions) (*ArielResult, error)
+	RunTemplate(context.Context, *AQLTemplate, map[string]interface{}, *RunOptions) (*ArielResult, error)
+	ExportResults(context.Context, string, io.Writer, *ExportOptions) (int, error)
+	ExportResultsToFile(context.Context, string, string, *ExportOptions) (int, error)
 }
 
 // AssetModel endpoint.
@@ -242,10 +263,22 @@ type Services interface{}
 // SIEM endpoint.
 type SIEM interface {
 	ListOffenses(context.Context, string, string, string, int, int) (*OffensePaginatedResponse, error)
+	ListOffensesRaw(context.Context, string, string, string, string, int, int) (io.ReadCloser, error)
 	GetOffense(context.Context, int, string) (*Offense, error)
-	UpdateOffense(context.Context, int, int, string, string, string, bool, bool) (*Offense, error)
-	ListOffenseNotes(context.Context, string) ([]*Note, int, error)
+	UpdateOffense(context.Context, int, *OffenseUpdate, string) (*Offense, error)
+	CloseOffense(context.Context, int, int) (*Offense, error)
+	AssignOffense(context.Context, int, string) (*Offense, error)
+	SetFollowUp(context.Context, int, bool) (*Offense, error)
+	ProtectOffense(context.Context, int, bool) (*Offense, error)
+	BulkUpdateOffenses(context.Context, string, []*BulkAction, *BulkOptions) ([]*BulkResult, error)
+	OffenseEvents(context.Context, int, *OffenseSearchOptions) ([]*ContributingEvent, error)
+	OffenseFlows(context.Context, int, *OffenseSearchOptions) ([]*ContributingFlow, error)
+	ComputeSOCMetrics(context.Context, *SOCMetricsOptions) (*SOCMetrics, error)
+	ListOffenseNotes(context.Context, int, string, string, int, int) (*NotesPaginatedResponse, error)
+	GetOffenseNote(context.Context, int, int, string) (*Note, error)
 	CreateOffenseNote(context.Context, int, string, string) (*Note, error)
+	DeleteOffenseNote(context.Context, int, int) error
+	AppendStructuredNote(context.Context, int, *StructuredNote) (*Note, error)
 	ListOffenseTypes(context.Context, string, string, string, int, int) (*OffenseTypesPaginatedResponse, error)
 	GetOffenseType(context.Context, string, string) (*OffenseType, error)
 	ListLocalDestinationAddress(context.Context, string, string, int, int) (*LocalDestinationAddressesPaginatedResponse, error)
@@ -255,6 +288,9 @@ type SIEM interface {
 	ListOffenseClosingReasons(context.Context, string, string, bool, bool, int, int) (*OffenseClosingReasonsPaginatedResponse, error)
 	CreateOffenseClosingReason(context.Context, string, string) (*OffenseClosingReason, error)
 	GetOffenseClosingReason(context.Context, int, string) (*OffenseClosingReason, error)
+	UpdateOffenseClosingReason(context.Context, int, string, string) (*OffenseClosingReason, error)
+	DeleteOffenseClosingReason(context.Context, int) error
+	SyncOffenseClosingReasons(context.Context, []string, *ClosingReasonSyncOptions) (*ClosingReasonSyncResult, error)
 }
 
 // StagedConfig endpoint.
@@ -0,0 +1,70 @@
+package goqradar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// Response formats supported by QRadar.
+const (
+	MIMETypeJSON = "application/json"
+	MIMETypeCSV  = "application/csv"
+	MIMETypeXML  = "application/xml"
+)
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// GetRaw retrieves the given resource in the requested format and returns the
+// body as is. The caller must close the returned body.
+func (c *Client) GetRaw(ctx context.Context, endpoint, mimeType string, opts ...Option) (io.ReadCloser, error) {
+	if mimeType == "" {
+		mimeType = MIMETypeJSON
+	}
+	opts = append(opts, WithHeader("Accept", mimeType))
+
+	// Do the request
+	resp, err := c.do(ctx, http.MethodGet, endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("error with the status code: %d: %s", resp.StatusCode, string(body))
+	}
+
+	return resp.Body, nil
+}
+
+// ListOffensesRaw returns the offenses in the requested format.
+func (endpoint *Endpoint) ListOffensesRaw(ctx context.Context, mimeType, fields, filter, sort string, min, max int) (io.ReadCloser, error) {
+	// Options
+	options := []Option{}
+	if fields != "" {
+		options = append(options, WithParam("fields", fields))
+	}
+	if filter != "" {
+		options = append(options, WithParam("filter", filter))
+	}
+	if sort != "" {
+		options = append(options, WithParam("sort", sort))
+	}
+	options = append(options, WithHeader("Range", fmt.Sprintf("items=%d-%d", min, max)))
+
+	return endpoint.client.GetRaw(ctx, "/siem/offenses", mimeType, options...)
+}
+
+// GetSearchesResultsRaw retrieves the results of the Ariel search in the requested format.
+func (endpoint *Endpoint) GetSearchesResultsRaw(ctx context.Context, searchID, mimeType string, min, max int) (io.ReadCloser, error) {
+	// Options
+	options := []Option{}
+	options = append(options, WithHeader("Range", fmt.Sprintf("items=%d-%d", min, max)))
+
+	return endpoint.client.GetRaw(ctx, "/ariel/searches/"+searchID+"/results", mimeType, options...)
+}
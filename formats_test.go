@@ -0,0 +1,59 @@
+package goqradar
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetSearchesResultsRaw(t *testing.T) {
+	var headers http.Header
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers, path = r.Header, r.URL.Path
+		w.Header().Set("Content-Type", MIMETypeCSV)
+		w.Write([]byte("sourceip,qid\n10.0.0.1,42\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	body, err := client.Ariel.GetSearchesResultsRaw(context.Background(), "abc", MIMETypeCSV, 0, 49)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	defer body.Close()
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if string(b) != "sourceip,qid\n10.0.0.1,42\n" {
+		t.Fatalf("body should be returned as is: %q", b)
+	}
+	if path != "/api/ariel/searches/abc/results" {
+		t.Fatalf("unexpected path: %s", path)
+	}
+
+	// The requested format overrides the default Accept header
+	if len(headers["Accept"]) != 1 || headers.Get("Accept") != MIMETypeCSV {
+		t.Fatalf("unexpected Accept header: %v", headers["Accept"])
+	}
+	if headers.Get("Range") != "items=0-49" {
+		t.Fatalf("unexpected Range header: %s", headers.Get("Range"))
+	}
+}
+
+func TestGetRawError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(422)
+		w.Write([]byte("invalid filter"))
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	if _, err := client.GetRaw(context.Background(), "/siem/offenses", MIMETypeXML); err == nil {
+		t.Fatal("should error with a status code other than 200")
+	}
+}
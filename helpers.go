@@ -1,12 +1,18 @@
 package goqradar
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func parseContentRange(cr string) (int, int, int, error) {
@@ -56,7 +62,7 @@ func parseContentRange(cr string) (int, int, int, error) {
 	return min, max, total, nil
 }
 
-func (c *Client) do(ctx context.Context, method, endpoint string, opts ...Option) (*http.Response, error) {
+func (c *Client) do(ctx context.Context, method, endpoint string, opts ...Option) (resp *http.Response, err error) {
 	// Options
 	var apiOptions options
 
@@ -68,6 +74,24 @@ func (c *Client) do(ctx context.Context, method, endpoint string, opts ...Option
 		}
 	}
 
+	// Start the span
+	template := endpointTemplate(endpoint)
+	ctx, span := c.tracer().Start(ctx, method+" "+template)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+		}
+		if resp != nil {
+			span.SetAttribute(AttributeStatusCode, resp.StatusCode)
+		}
+		span.End()
+	}()
+	span.SetAttribute(AttributeEndpoint, template)
+	span.SetAttribute(AttributeMethod, method)
+	if apiOptions.Headers != nil && apiOptions.Headers.Get("Range") != "" {
+		span.SetAttribute(AttributeRange, apiOptions.Headers.Get("Range"))
+	}
+
 	// Raw URL
 	rawURL := fmt.Sprintf("%s/api%s", c.BaseURL, endpoint)
 
@@ -82,8 +106,17 @@ func (c *Client) do(ctx context.Context, method, endpoint string, opts ...Option
 		queryURL.RawQuery = apiOptions.Params.Encode()
 	}
 
+	// Prepare the body
+	var body []byte
+	if apiOptions.Data != nil {
+		body, err = json.Marshal(apiOptions.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error while marshalling the data: %s", err)
+		}
+	}
+
 	// Initialize request
-	req, err := http.NewRequestWithContext(ctx, method, queryURL.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, method, queryURL.String(), bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -93,22 +126,73 @@ func (c *Client) do(ctx context.Context, method, endpoint string, opts ...Option
 	headers.Add("Accept", "application/json")
 	headers.Add("Version", c.Version)
 	headers.Add("SEC", c.Token)
+	if body != nil {
+		headers.Add("Content-Type", "application/json")
+	}
 
-	// Optional headers
+	// Optional headers, which can override the default ones
 	if apiOptions.Headers != nil {
 		for k := range *apiOptions.Headers {
-			headers.Add(k, apiOptions.Headers.Get(k))
+			headers.Set(k, apiOptions.Headers.Get(k))
 		}
 	}
 
+	// Propagate the span context
+	if injector, ok := c.tracer().(HeaderInjector); ok {
+		injector.Inject(ctx, headers)
+	}
+
 	// Assign new headers
 	req.Header = headers
 
 	// Do the query
-	resp, err := c.client.Do(req)
+	start := time.Now()
+	resp, err = c.client.Do(req)
 	if err != nil {
+		if c.Logger != nil {
+			c.logRequest(req, body, nil, nil, time.Since(start), err)
+		}
 		return nil, fmt.Errorf("error while doing the request: %s", err)
 	}
 
-	return resp, err
+	// Log the request, with the beginning of the response body only, so that
+	// the rest of the body is still streamed to the caller
+	if c.Logger != nil {
+		var responseBody []byte
+		if c.LogBodies {
+			responseBody, err = ioutil.ReadAll(io.LimitReader(resp.Body, int64(c.maxLogBodySize())+1))
+			if err != nil {
+				resp.Body.Close()
+				return nil, fmt.Errorf("error while reading the response: %s", err)
+			}
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(responseBody), resp.Body), resp.Body}
+		}
+		c.logRequest(req, body, resp, responseBody, time.Since(start), nil)
+	}
+
+	return resp, nil
+}
+
+// writeJSONFile marshals the value into the file. The file is written then
+// renamed, so that it is never partially written.
+func writeJSONFile(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error while marshalling: %s", err)
+	}
+
+	err = ioutil.WriteFile(path+".tmp", b, 0644)
+	if err != nil {
+		return fmt.Errorf("error while writing the file: %s", err)
+	}
+
+	err = os.Rename(path+".tmp", path)
+	if err != nil {
+		return fmt.Errorf("error while writing the file: %s", err)
+	}
+
+	return nil
 }
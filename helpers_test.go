@@ -1,9 +1,56 @@
 package goqradar
 
 import (
+	"io"
+	"net/http"
+	"sync"
 	"testing"
 )
 
+// bodyTracker is a transport which counts the response bodies not closed.
+type bodyTracker struct {
+	mu   sync.Mutex
+	open int
+}
+
+func (b *bodyTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.open++
+	b.mu.Unlock()
+	resp.Body = &trackedBody{ReadCloser: resp.Body, tracker: b}
+
+	return resp, nil
+}
+
+// unclosed returns the number of response bodies not closed.
+func (b *bodyTracker) unclosed() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.open
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	tracker *bodyTracker
+	once    sync.Once
+}
+
+func (t *trackedBody) Close() error {
+	t.once.Do(func() {
+		t.tracker.mu.Lock()
+		t.tracker.open--
+		t.tracker.mu.Unlock()
+	})
+
+	return t.ReadCloser.Close()
+}
+
 func TestParseContentRange(t *testing.T) {
 	cr1 := "0-10/40"
 	_, _, _, err := parseContentRange(cr1)
@@ -0,0 +1,170 @@
+package goqradar
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultMaxLogBodySize = 2048
+	redacted              = "[REDACTED]"
+)
+
+var redactedHeaders = []string{"SEC", "Authorization", "Cookie", "Set-Cookie"}
+
+var (
+	formSecretPattern = regexp.MustCompile(`(?i)((?:old_)?password|token|sec)=([^&\s]*)`)
+
+	// jsonSecretPattern redacts the truncated JSON bodies, which cannot be unmarshalled
+	jsonSecretPattern = regexp.MustCompile(`(?i)("[^"]*(?:password|token|authorization)[^"]*"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]*)`)
+)
+
+//------------------------------------------------------------------------------
+// Interfaces
+//------------------------------------------------------------------------------
+
+// Logger is a structured logger. The key/value pairs follow the convention of
+// the common structured loggers (logr, zap sugared logger, slog).
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// logRequest logs the request and its response at debug level.
+func (c *Client) logRequest(req *http.Request, requestBody []byte, resp *http.Response, responseBody []byte, duration time.Duration, err error) {
+	keysAndValues := []interface{}{
+		"method", req.Method,
+		"path", req.URL.Path,
+		"params", redactValues(req.URL.Query()),
+		"headers", redactHeaders(req.Header),
+		"range", req.Header.Get("Range"),
+		"duration", duration,
+	}
+
+	if resp != nil {
+		keysAndValues = append(keysAndValues,
+			"status", resp.StatusCode,
+			"content_range", resp.Header.Get("Content-Range"),
+		)
+	}
+
+	if c.LogBodies {
+		if requestBody != nil {
+			keysAndValues = append(keysAndValues, "request_body", c.formatBody(requestBody))
+		}
+		if responseBody != nil {
+			keysAndValues = append(keysAndValues, "response_body", c.formatBody(responseBody))
+		}
+	}
+
+	if err != nil {
+		keysAndValues = append(keysAndValues, "error", err.Error())
+	}
+
+	c.Logger.Debug("qradar request", keysAndValues...)
+}
+
+// maxLogBodySize returns the size after which the logged bodies are truncated.
+func (c *Client) maxLogBodySize() int {
+	if c.MaxLogBodySize <= 0 {
+		return defaultMaxLogBodySize
+	}
+
+	return c.MaxLogBodySize
+}
+
+// formatBody redacts then truncates a body.
+func (c *Client) formatBody(body []byte) string {
+	max := c.maxLogBodySize()
+
+	s := redactBody(body)
+	if len(s) > max {
+		// Do not split a character
+		for max > 0 && !utf8.RuneStart(s[max]) {
+			max--
+		}
+		return s[:max] + "...(truncated)"
+	}
+
+	return s
+}
+
+// redactHeaders returns a copy of the headers with the secrets redacted.
+func redactHeaders(headers http.Header) http.Header {
+	result := http.Header{}
+	for k, v := range headers {
+		result[k] = v
+	}
+
+	for _, name := range redactedHeaders {
+		if result.Get(name) != "" {
+			result.Set(name, redacted)
+		}
+	}
+
+	return result
+}
+
+// redactValues returns a copy of the query parameters with the secrets redacted.
+func redactValues(values url.Values) url.Values {
+	result := url.Values{}
+	for k, v := range values {
+		if isSecretKey(k) {
+			result.Set(k, redacted)
+			continue
+		}
+		result[k] = v
+	}
+
+	return result
+}
+
+// redactBody redacts the secrets of a JSON or form encoded body.
+func redactBody(body []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		s := jsonSecretPattern.ReplaceAllString(string(body), `$1"`+redacted+`"`)
+		return formSecretPattern.ReplaceAllString(s, "$1="+redacted)
+	}
+
+	b, err := json.Marshal(redactJSON(value))
+	if err != nil {
+		return redacted
+	}
+
+	return string(b)
+}
+
+func redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if isSecretKey(k) {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redactJSON(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactJSON(item)
+		}
+	}
+
+	return value
+}
+
+// isSecretKey returns true if the key holds a password or a token.
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+
+	return strings.Contains(key, "password") || strings.Contains(key, "token") || key == "sec" || key == "authorization"
+}
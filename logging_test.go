@@ -0,0 +1,118 @@
+package goqradar
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// recordingLogger records the key/value pairs of the last message.
+type recordingLogger struct {
+	keysAndValues map[string]interface{}
+}
+
+func (l *recordingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.keysAndValues = map[string]interface{}{}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		l.keysAndValues[keysAndValues[i].(string)] = keysAndValues[i+1]
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	body := redactBody([]byte(`{"username":"admin","password":"secret","old_password":"older","nested":{"token":"abc"}}`))
+	if strings.Contains(body, "secret") || strings.Contains(body, "older") || strings.Contains(body, "abc") {
+		t.Fatalf("body should be redacted: %s", body)
+	}
+	if !strings.Contains(body, "admin") {
+		t.Fatalf("body should keep the username: %s", body)
+	}
+
+	truncated := redactBody([]byte(`{"name":"x","auth_token":"abc","password":"sec`))
+	if strings.Contains(truncated, "abc") || strings.Contains(truncated, `:"sec`) {
+		t.Fatalf("truncated body should be redacted: %s", truncated)
+	}
+
+	form := redactBody([]byte("password=secret&name=x"))
+	if strings.Contains(form, "secret") {
+		t.Fatalf("form should be redacted: %s", form)
+	}
+}
+
+func TestRedactHeadersAndValues(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("SEC", "token")
+	headers.Set("Authorization", "Basic abc")
+	headers.Set("Range", "items=0-49")
+
+	result := redactHeaders(headers)
+	if result.Get("SEC") != redacted || result.Get("Authorization") != redacted {
+		t.Fatal("secret headers should be redacted")
+	}
+	if headers.Get("SEC") != "token" {
+		t.Fatal("original headers should not be modified")
+	}
+	if result.Get("Range") != "items=0-49" {
+		t.Fatal("range header should be kept")
+	}
+
+	values := redactValues(url.Values{"password": {"secret"}, "fields": {"id"}})
+	if values.Get("password") != redacted || values.Get("fields") != "id" {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
+func TestFormatBodyTruncation(t *testing.T) {
+	c := &Client{MaxLogBodySize: 4}
+
+	// "é" is two bytes long, the limit falls in the middle of the second one
+	body := c.formatBody([]byte("abcé"))
+	if body != "abc...(truncated)" {
+		t.Fatalf("unexpected body: %s", body)
+	}
+	if !utf8.ValidString(body) {
+		t.Fatalf("body should be valid UTF-8: %q", body)
+	}
+
+	if body := c.formatBody([]byte("abcd")); body != "abcd" {
+		t.Fatalf("body should not be truncated: %s", body)
+	}
+}
+
+func TestLogResponseBodyPrefix(t *testing.T) {
+	payload := `{"token":"abc","items":"` + strings.Repeat("x", 1000) + `"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	logger := &recordingLogger{}
+	client := NewClient(nil, server.URL, "token")
+	client.Logger = logger
+	client.LogBodies = true
+	client.MaxLogBodySize = 32
+
+	body, err := client.GetRaw(context.Background(), "/siem/offenses", MIMETypeJSON)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	defer body.Close()
+
+	// The caller still reads the whole body
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if string(b) != payload {
+		t.Fatalf("unexpected body: %d bytes", len(b))
+	}
+
+	logged, _ := logger.keysAndValues["response_body"].(string)
+	if !strings.HasSuffix(logged, "...(truncated)") || strings.Contains(logged, "abc") || len(logged) > 64 {
+		t.Fatalf("unexpected logged body: %s", logged)
+	}
+}
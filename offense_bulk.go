@@ -0,0 +1,336 @@
+package goqradar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBulkConcurrency = 5
+	defaultBulkPageSize    = 100
+	bulkJobSaveInterval    = time.Second
+)
+
+// Types of the bulk actions.
+const (
+	BulkActionClose    = "close"
+	BulkActionAssign   = "assign"
+	BulkActionFollowUp = "follow_up"
+	BulkActionNote     = "note"
+)
+
+// Statuses of the bulk results.
+const (
+	BulkStatusPlanned = "PLANNED"
+	BulkStatusDone    = "DONE"
+	BulkStatusFailed  = "FAILED"
+)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// BulkAction is an action applied to every selected offense.
+type BulkAction struct {
+	Type string `json:"type"`
+
+	// ClosingReasonID is the closing reason of the close action.
+	ClosingReasonID int `json:"closing_reason_id,omitempty"`
+
+	// AssignedTo is the user of the assign action.
+	AssignedTo string `json:"assigned_to,omitempty"`
+
+	// FollowUp is the flag of the follow-up action.
+	FollowUp bool `json:"follow_up,omitempty"`
+
+	// NoteText is the text of the note action.
+	NoteText string `json:"note_text,omitempty"`
+}
+
+// BulkOptions are the options of a bulk operation.
+type BulkOptions struct {
+	// Concurrency is the maximum number of offenses updated concurrently. Default is 5.
+	Concurrency int
+
+	// PageSize is the number of offenses selected per request. Default is 100.
+	PageSize int
+
+	// DryRun only selects the offenses, the results are planned.
+	DryRun bool
+
+	// JobFile is the file where the selected offenses and the results are
+	// saved, at most every second and at the end. An interrupted operation with
+	// the same filter and actions resumes from it, and the failed offenses are
+	// retried from their first action that did not succeed. It is optional.
+	JobFile string
+
+	// Progress is called after every offense. The calls are serialized.
+	Progress func(*BulkResult)
+}
+
+// BulkResult is the result of a bulk operation on an offense.
+type BulkResult struct {
+	OffenseID int    `json:"offense_id"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+
+	// Applied is the number of actions which succeeded.
+	Applied int `json:"applied"`
+}
+
+type bulkJob struct {
+	Filter     string              `json:"filter"`
+	Actions    []*BulkAction       `json:"actions"`
+	OffenseIDs []int               `json:"offense_ids"`
+	Results    map[int]*BulkResult `json:"results"`
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// BulkUpdateOffenses selects the offenses with the filter, such as
+// "status = 'OPEN' and magnitude < 3", and applies the actions in order to
+// every offense. The offenses are selected before being updated, so that the
+// actions do not move the pages. A failed action does not stop the operation,
+// the results report the status of every offense, and are nil for the
+// offenses not processed when the context is canceled.
+func (endpoint *Endpoint) BulkUpdateOffenses(ctx context.Context, filter string, actions []*BulkAction, opts *BulkOptions) ([]*BulkResult, error) {
+	if len(actions) == 0 {
+		return nil, fmt.Errorf("no action to apply")
+	}
+	for _, action := range actions {
+		if err := action.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if opts == nil {
+		opts = &BulkOptions{}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+
+	// Resume the job, or select the offenses
+	job, err := loadBulkJob(opts.JobFile, filter, actions)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		job = &bulkJob{Filter: filter, Actions: actions, Results: map[int]*BulkResult{}}
+		job.OffenseIDs, err = endpoint.selectOffenses(ctx, filter, opts.PageSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Keep the offenses already done
+	results := make([]*BulkResult, len(job.OffenseIDs))
+	for i, id := range job.OffenseIDs {
+		if result, ok := job.Results[id]; ok && result.Status == BulkStatusDone {
+			results[i] = result
+		}
+	}
+
+	if opts.DryRun {
+		for i, id := range job.OffenseIDs {
+			if results[i] == nil {
+				results[i] = &BulkResult{OffenseID: id, Status: BulkStatusPlanned}
+			}
+		}
+		return results, nil
+	}
+
+	save := func() error {
+		if opts.JobFile == "" {
+			return nil
+		}
+		return writeJSONFile(opts.JobFile, job)
+	}
+	if err := save(); err != nil {
+		return nil, fmt.Errorf("error while saving the job: %s", err)
+	}
+
+	// The workers only apply the actions, the job is updated and saved here
+	var wg sync.WaitGroup
+	done := make(chan *BulkResult, len(job.OffenseIDs))
+	indexes := map[int]int{}
+	semaphore := make(chan struct{}, concurrency)
+	for i, id := range job.OffenseIDs {
+		if results[i] != nil {
+			continue
+		}
+		indexes[id] = i
+
+		// Resume after the actions which already succeeded
+		applied := 0
+		if result, ok := job.Results[id]; ok && result.Applied < len(actions) {
+			applied = result.Applied
+		}
+
+		wg.Add(1)
+		go func(id, applied int) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+
+			result := &BulkResult{OffenseID: id, Status: BulkStatusDone}
+			n, err := endpoint.applyBulkActions(ctx, id, actions[applied:])
+			if err != nil {
+				result.Status, result.Error = BulkStatusFailed, err.Error()
+			}
+			result.Applied = applied + n
+			done <- result
+		}(id, applied)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var saveErr error
+	lastSave := time.Now()
+	for result := range done {
+		results[indexes[result.OffenseID]] = result
+		job.Results[result.OffenseID] = result
+		if opts.Progress != nil {
+			opts.Progress(result)
+		}
+
+		if time.Since(lastSave) >= bulkJobSaveInterval {
+			if err := save(); err != nil && saveErr == nil {
+				saveErr = fmt.Errorf("error while saving the job: %s", err)
+			}
+			lastSave = time.Now()
+		}
+	}
+	if err := save(); err != nil && saveErr == nil {
+		saveErr = fmt.Errorf("error while saving the job: %s", err)
+	}
+
+	if saveErr != nil {
+		return results, saveErr
+	}
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+
+	return results, nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+func (a *BulkAction) validate() error {
+	switch a.Type {
+	case BulkActionClose:
+		if a.ClosingReasonID <= 0 {
+			return fmt.Errorf("the close action requires a closing reason")
+		}
+	case BulkActionAssign:
+		if a.AssignedTo == "" {
+			return fmt.Errorf("the assign action requires a user")
+		}
+	case BulkActionFollowUp:
+	case BulkActionNote:
+		if a.NoteText == "" {
+			return fmt.Errorf("the note action requires a text")
+		}
+	default:
+		return fmt.Errorf("invalid bulk action: %s", a.Type)
+	}
+
+	return nil
+}
+
+// selectOffenses returns the IDs of the offenses matching the filter.
+func (endpoint *Endpoint) selectOffenses(ctx context.Context, filter string, pageSize int) ([]int, error) {
+	if pageSize <= 0 {
+		pageSize = defaultBulkPageSize
+	}
+
+	ids := []int{}
+	for min := 0; ; min += pageSize {
+		page, err := endpoint.ListOffenses(ctx, "id", filter, "+id", min, min+pageSize-1)
+		if err != nil {
+			return nil, fmt.Errorf("error while selecting the offenses: %s", err)
+		}
+		for _, offense := range page.Offenses {
+			ids = append(ids, offense.ID)
+		}
+		if len(page.Offenses) < pageSize || min+pageSize >= page.Total {
+			break
+		}
+	}
+
+	return ids, nil
+}
+
+// applyBulkActions applies the actions in order, stops at the first error, and
+// returns the number of actions which succeeded.
+func (endpoint *Endpoint) applyBulkActions(ctx context.Context, id int, actions []*BulkAction) (int, error) {
+	for i, action := range actions {
+		var err error
+		switch action.Type {
+		case BulkActionClose:
+			_, err = endpoint.CloseOffense(ctx, id, action.ClosingReasonID)
+		case BulkActionAssign:
+			_, err = endpoint.AssignOffense(ctx, id, action.AssignedTo)
+		case BulkActionFollowUp:
+			_, err = endpoint.SetFollowUp(ctx, id, action.FollowUp)
+		case BulkActionNote:
+			_, err = endpoint.CreateOffenseNote(ctx, id, action.NoteText, "id")
+		}
+		if err != nil {
+			return i, fmt.Errorf("error with the %s action: %s", action.Type, err)
+		}
+	}
+
+	return len(actions), nil
+}
+
+// loadBulkJob returns the job of the file, or nil if there is no file.
+func loadBulkJob(path, filter string, actions []*BulkAction) (*bulkJob, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while reading the job: %s", err)
+	}
+
+	var job *bulkJob
+	err = json.Unmarshal(b, &job)
+	if err != nil {
+		return nil, fmt.Errorf("error while unmarshalling the job: %s", err)
+	}
+	if job == nil {
+		return nil, fmt.Errorf("the job file %s is empty", path)
+	}
+
+	if job.Filter != filter || !reflect.DeepEqual(job.Actions, actions) {
+		return nil, fmt.Errorf("the job file %s belongs to another operation", path)
+	}
+	if job.Results == nil {
+		job.Results = map[int]*BulkResult{}
+	}
+
+	return job, nil
+}
@@ -0,0 +1,94 @@
+package goqradar
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBulkUpdateOffenses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goqradar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		mu      sync.Mutex
+		updates = map[string]int{}
+		failing = true
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/siem/offenses" {
+			w.Header().Set("Content-Range", "items 0-2/3")
+			w.Write([]byte(`[{"id":1},{"id":2},{"id":3}]`))
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		updates[r.URL.Path]++
+		if r.URL.Path == "/api/siem/offenses/2" && failing {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/notes") {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	actions := []*BulkAction{{Type: BulkActionNote, NoteText: "Bulk closed"}, {Type: BulkActionClose, ClosingReasonID: 1}}
+	opts := &BulkOptions{JobFile: filepath.Join(dir, "job.json")}
+
+	// Dry-run
+	results, err := client.SIEM.BulkUpdateOffenses(context.Background(), "status = 'OPEN'", actions, &BulkOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if len(results) != 3 || results[0].Status != BulkStatusPlanned || len(updates) != 0 {
+		t.Fatalf("unexpected dry-run: %+v %v", results, updates)
+	}
+
+	results, err = client.SIEM.BulkUpdateOffenses(context.Background(), "status = 'OPEN'", actions, opts)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if results[0].Status != BulkStatusDone || results[1].Status != BulkStatusFailed || results[2].Status != BulkStatusDone {
+		t.Fatalf("unexpected results: %+v %+v %+v", results[0], results[1], results[2])
+	}
+	if results[0].Applied != 2 || results[1].Applied != 1 {
+		t.Fatalf("unexpected applied actions: %+v %+v", results[0], results[1])
+	}
+
+	// Resume, only the failed action of the failed offense is retried
+	failing = false
+	results, err = client.SIEM.BulkUpdateOffenses(context.Background(), "status = 'OPEN'", actions, opts)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if results[1].Status != BulkStatusDone || results[1].Applied != 2 || updates["/api/siem/offenses/1"] != 1 || updates["/api/siem/offenses/2"] != 2 || updates["/api/siem/offenses/2/notes"] != 1 {
+		t.Fatalf("unexpected resume: %+v %v", results[1], updates)
+	}
+
+	// Another operation cannot use the job file
+	if _, err := client.SIEM.BulkUpdateOffenses(context.Background(), "status = 'HIDDEN'", actions, opts); err == nil {
+		t.Fatal("should error with the job file of another operation")
+	}
+
+	// A null job file is an error
+	if err := ioutil.WriteFile(opts.JobFile, []byte("null"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SIEM.BulkUpdateOffenses(context.Background(), "status = 'OPEN'", actions, opts); err == nil {
+		t.Fatal("should error with a null job file")
+	}
+}
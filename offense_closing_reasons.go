@@ -0,0 +1,210 @@
+package goqradar
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const defaultClosingReasonsPageSize = 50
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// ClosingReasonSyncOptions are the options of the sync of the closing reasons.
+type ClosingReasonSyncOptions struct {
+	// Prune deletes the closing reasons which are not desired. The reserved
+	// reasons are never deleted.
+	Prune bool
+
+	// DryRun computes the changes without applying them.
+	DryRun bool
+}
+
+// ClosingReasonSyncResult is the result of the sync of the closing reasons of a console.
+type ClosingReasonSyncResult struct {
+	// Console is the name of the console, set by SyncFleetClosingReasons.
+	Console string
+
+	// Created are the created reasons. With a dry-run, their ID is 0.
+	Created []*OffenseClosingReason
+
+	// Updated are the reasons whose case or spacing differed, with their new text.
+	Updated []*OffenseClosingReason
+
+	// Deleted are the pruned reasons.
+	Deleted []*OffenseClosingReason
+
+	// Unchanged are the desired reasons which already exist.
+	Unchanged []*OffenseClosingReason
+
+	// Error is the error of the console, set by SyncFleetClosingReasons.
+	Error error
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// SyncOffenseClosingReasons ensures that the desired closing reasons exist.
+// The texts are compared without case and surrounding spaces: an existing
+// reason which only differs by them is updated, unless it is reserved. The
+// deleted reasons cannot be restored, so a desired reason which only exists
+// as deleted is created again.
+//
+// On error, the result contains the changes which were applied before it.
+func (endpoint *Endpoint) SyncOffenseClosingReasons(ctx context.Context, desired []string, opts *ClosingReasonSyncOptions) (*ClosingReasonSyncResult, error) {
+	if opts == nil {
+		opts = &ClosingReasonSyncOptions{}
+	}
+
+	// Normalize the desired reasons
+	texts := []string{}
+	seen := map[string]bool{}
+	for _, text := range desired {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return nil, fmt.Errorf("the text of the closing reason is required")
+		}
+		if seen[closingReasonKey(text)] {
+			continue
+		}
+		seen[closingReasonKey(text)] = true
+		texts = append(texts, text)
+	}
+
+	reasons, err := listAllOffenseClosingReasons(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing the closing reasons: %s", err)
+	}
+
+	// Index the active reasons, the oldest first
+	sort.Slice(reasons, func(i, j int) bool { return reasons[i].ID < reasons[j].ID })
+	existing := map[string]*OffenseClosingReason{}
+	for _, reason := range reasons {
+		if reason.IsDeleted {
+			continue
+		}
+		if _, ok := existing[closingReasonKey(reason.Text)]; !ok {
+			existing[closingReasonKey(reason.Text)] = reason
+		}
+	}
+
+	result := &ClosingReasonSyncResult{}
+	kept := map[int]bool{}
+	for _, text := range texts {
+		reason, ok := existing[closingReasonKey(text)]
+		switch {
+		case !ok:
+			created := &OffenseClosingReason{Text: text}
+			if !opts.DryRun {
+				created, err = endpoint.CreateOffenseClosingReason(ctx, text, "")
+				if err != nil {
+					return result, fmt.Errorf("error while creating the closing reason %q: %s", text, err)
+				}
+			}
+			result.Created = append(result.Created, created)
+		case reason.Text != text && !reason.IsReserved:
+			kept[reason.ID] = true
+			updated := &OffenseClosingReason{ID: reason.ID, Text: text}
+			if !opts.DryRun {
+				updated, err = endpoint.UpdateOffenseClosingReason(ctx, reason.ID, text, "")
+				if err != nil {
+					return result, fmt.Errorf("error while updating the closing reason %d: %s", reason.ID, err)
+				}
+			}
+			result.Updated = append(result.Updated, updated)
+		default:
+			kept[reason.ID] = true
+			result.Unchanged = append(result.Unchanged, reason)
+		}
+	}
+
+	if !opts.Prune {
+		return result, nil
+	}
+
+	for _, reason := range reasons {
+		if reason.IsDeleted || reason.IsReserved || kept[reason.ID] {
+			continue
+		}
+		if !opts.DryRun {
+			if err := endpoint.DeleteOffenseClosingReason(ctx, reason.ID); err != nil {
+				return result, fmt.Errorf("error while deleting the closing reason %d: %s", reason.ID, err)
+			}
+		}
+		result.Deleted = append(result.Deleted, reason)
+	}
+
+	return result, nil
+}
+
+// SyncFleetClosingReasons syncs the closing reasons of every console
+// concurrently, the consoles being indexed by name. A failed console does not
+// stop the others: the results, sorted by console, contain the error of each
+// console, and an error is returned if any console failed.
+func SyncFleetClosingReasons(ctx context.Context, consoles map[string]SIEM, desired []string, opts *ClosingReasonSyncOptions) ([]*ClosingReasonSyncResult, error) {
+	results := make([]*ClosingReasonSyncResult, 0, len(consoles))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, siem := range consoles {
+		wg.Add(1)
+		go func(name string, siem SIEM) {
+			defer wg.Done()
+
+			result, err := siem.SyncOffenseClosingReasons(ctx, desired, opts)
+			if result == nil {
+				result = &ClosingReasonSyncResult{}
+			}
+			result.Console = name
+			result.Error = err
+
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+		}(name, siem)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Console < results[j].Console })
+
+	failed := []string{}
+	for _, result := range results {
+		if result.Error != nil {
+			failed = append(failed, result.Console)
+		}
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("error while syncing the closing reasons of %d of %d consoles: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+
+	return results, nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// listAllOffenseClosingReasons returns all the closing reasons, including the
+// deleted and the reserved ones, page by page.
+func listAllOffenseClosingReasons(ctx context.Context, siem SIEM) ([]*OffenseClosingReason, error) {
+	reasons := []*OffenseClosingReason{}
+	for min := 0; ; min += defaultClosingReasonsPageSize {
+		page, err := siem.ListOffenseClosingReasons(ctx, "", "", true, true, min, min+defaultClosingReasonsPageSize-1)
+		if err != nil {
+			return nil, err
+		}
+		reasons = append(reasons, page.OffenseClosingReasons...)
+		if len(page.OffenseClosingReasons) < defaultClosingReasonsPageSize || min+defaultClosingReasonsPageSize >= page.Total {
+			return reasons, nil
+		}
+	}
+}
+
+func closingReasonKey(text string) string {
+	return strings.ToLower(strings.TrimSpace(text))
+}
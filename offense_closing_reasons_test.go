@@ -0,0 +1,226 @@
+package goqradar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeClosingReasonsServer serves the closing reasons endpoints from memory.
+type fakeClosingReasonsServer struct {
+	mu      sync.Mutex
+	reasons map[int]*OffenseClosingReason
+	nextID  int
+	calls   []string
+}
+
+func newFakeClosingReasonsServer(reasons ...*OffenseClosingReason) *fakeClosingReasonsServer {
+	s := &fakeClosingReasonsServer{reasons: map[int]*OffenseClosingReason{}, nextID: 100}
+	for _, reason := range reasons {
+		s.reasons[reason.ID] = reason
+	}
+	return s
+}
+
+func (s *fakeClosingReasonsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/siem/offense_closing_reasons")
+	if r.Method != http.MethodGet {
+		s.calls = append(s.calls, r.Method+" "+path)
+	}
+
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			reasons := []*OffenseClosingReason{}
+			for _, reason := range s.reasons {
+				if reason.IsDeleted && r.URL.Query().Get("include_deleted") != "true" {
+					continue
+				}
+				if reason.IsReserved && r.URL.Query().Get("include_reserved") != "true" {
+					continue
+				}
+				reasons = append(reasons, reason)
+			}
+			sort.Slice(reasons, func(i, j int) bool { return reasons[i].ID < reasons[j].ID })
+			w.Header().Set("Content-Range", fmt.Sprintf("items 0-%d/%d", len(reasons)-1, len(reasons)))
+			json.NewEncoder(w).Encode(reasons)
+		case http.MethodPost:
+			reason := &OffenseClosingReason{ID: s.nextID, Text: r.URL.Query().Get("reason")}
+			s.nextID++
+			s.reasons[reason.ID] = reason
+			w.WriteHeader(201)
+			json.NewEncoder(w).Encode(reason)
+		}
+		return
+	}
+
+	id, _ := strconv.Atoi(strings.TrimPrefix(path, "/"))
+	reason, ok := s.reasons[id]
+	if !ok {
+		w.WriteHeader(404)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(reason)
+	case http.MethodPost:
+		reason.Text = r.URL.Query().Get("reason")
+		json.NewEncoder(w).Encode(reason)
+	case http.MethodDelete:
+		reason.IsDeleted = true
+		w.WriteHeader(204)
+	}
+}
+
+func TestCreateOffenseClosingReason(t *testing.T) {
+	var query map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.WriteHeader(201)
+		w.Write([]byte(`{"id":101,"text":"Benign"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	reason, err := client.SIEM.CreateOffenseClosingReason(context.Background(), "Benign", "id,text")
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if reason.ID != 101 {
+		t.Fatalf("unexpected reason: %+v", reason)
+	}
+	if query["reason"][0] != "Benign" || query["fields"][0] != "id,text" || len(query) != 2 {
+		t.Fatalf("unexpected parameters: %v", query)
+	}
+}
+
+func TestDeleteOffenseClosingReason(t *testing.T) {
+	fake := newFakeClosingReasonsServer(
+		&OffenseClosingReason{ID: 1, Text: "Non-Issue", IsReserved: true},
+		&OffenseClosingReason{ID: 2, Text: "Old", IsDeleted: true},
+		&OffenseClosingReason{ID: 3, Text: "Custom"},
+	)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tracker := &bodyTracker{}
+	client := NewClient(&http.Client{Transport: tracker}, server.URL, "token")
+	ctx := context.Background()
+
+	if err := client.SIEM.DeleteOffenseClosingReason(ctx, 1); err == nil {
+		t.Fatal("should error with a reserved reason")
+	}
+	if _, err := client.SIEM.UpdateOffenseClosingReason(ctx, 1, "Other", ""); err == nil {
+		t.Fatal("should error with a reserved reason")
+	}
+	if _, err := client.SIEM.UpdateOffenseClosingReason(ctx, 2, "Other", ""); err == nil {
+		t.Fatal("should error with a deleted reason")
+	}
+	if err := client.SIEM.DeleteOffenseClosingReason(ctx, 2); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if err := client.SIEM.DeleteOffenseClosingReason(ctx, 3); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	// Only the active reason is deleted
+	if strings.Join(fake.calls, ",") != "DELETE /3" {
+		t.Fatalf("unexpected calls: %v", fake.calls)
+	}
+	if n := tracker.unclosed(); n != 0 {
+		t.Fatalf("%d response bodies are not closed", n)
+	}
+}
+
+func TestSyncOffenseClosingReasons(t *testing.T) {
+	fake := newFakeClosingReasonsServer(
+		&OffenseClosingReason{ID: 1, Text: "Non-Issue", IsReserved: true},
+		&OffenseClosingReason{ID: 2, Text: "False-Positive, Tuned", IsReserved: true},
+		&OffenseClosingReason{ID: 3, Text: "benign activity"},
+		&OffenseClosingReason{ID: 4, Text: "Pentest", IsDeleted: true},
+		&OffenseClosingReason{ID: 5, Text: "Obsolete"},
+	)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	desired := []string{"non-issue", "Benign activity", "Pentest", " pentest "}
+
+	// Dry-run
+	result, err := client.SIEM.SyncOffenseClosingReasons(context.Background(), desired, &ClosingReasonSyncOptions{Prune: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if len(fake.calls) != 0 {
+		t.Fatalf("should not change anything but calls are: %v", fake.calls)
+	}
+	if len(result.Created) != 1 || result.Created[0].Text != "Pentest" || result.Created[0].ID != 0 {
+		t.Fatalf("unexpected created reasons: %+v", result.Created)
+	}
+	if len(result.Updated) != 1 || result.Updated[0].ID != 3 || result.Updated[0].Text != "Benign activity" {
+		t.Fatalf("unexpected updated reasons: %+v", result.Updated)
+	}
+	if len(result.Deleted) != 1 || result.Deleted[0].ID != 5 {
+		t.Fatalf("unexpected deleted reasons: %+v", result.Deleted)
+	}
+	if len(result.Unchanged) != 1 || result.Unchanged[0].ID != 1 {
+		t.Fatalf("unexpected unchanged reasons: %+v", result.Unchanged)
+	}
+
+	// Apply
+	if _, err := client.SIEM.SyncOffenseClosingReasons(context.Background(), desired, &ClosingReasonSyncOptions{Prune: true}); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if strings.Join(fake.calls, ",") != "POST /3,POST ,DELETE /5" {
+		t.Fatalf("unexpected calls: %v", fake.calls)
+	}
+
+	// The second sync changes nothing
+	fake.calls = nil
+	result, err = client.SIEM.SyncOffenseClosingReasons(context.Background(), desired, &ClosingReasonSyncOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if len(fake.calls) != 0 || len(result.Unchanged) != 3 {
+		t.Fatalf("should be in sync but calls are %v and result is %+v", fake.calls, result)
+	}
+}
+
+func TestSyncFleetClosingReasons(t *testing.T) {
+	fake := newFakeClosingReasonsServer(&OffenseClosingReason{ID: 1, Text: "Non-Issue", IsReserved: true})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer down.Close()
+
+	consoles := map[string]SIEM{
+		"paris":  NewClient(nil, server.URL, "token").SIEM,
+		"berlin": NewClient(nil, down.URL, "token").SIEM,
+	}
+	results, err := SyncFleetClosingReasons(context.Background(), consoles, []string{"Benign"}, nil)
+	if err == nil {
+		t.Fatal("should error with a failed console")
+	}
+	if len(results) != 2 || results[0].Console != "berlin" || results[1].Console != "paris" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if results[0].Error == nil || results[1].Error != nil {
+		t.Fatalf("unexpected errors: %v, %v", results[0].Error, results[1].Error)
+	}
+	if len(results[1].Created) != 1 || results[1].Created[0].ID != 100 {
+		t.Fatalf("unexpected created reasons: %+v", results[1].Created)
+	}
+}
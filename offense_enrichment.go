@@ -0,0 +1,332 @@
+package goqradar
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultEnrichmentConcurrency = 5
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// EnrichedOffense is an offense with its references resolved.
+type EnrichedOffense struct {
+	*Offense
+
+	SourceAddresses           []*SourceAddress           `json:"source_addresses"`
+	LocalDestinationAddresses []*LocalDestinationAddress `json:"local_destination_addresses"`
+	SourceIPs                 []string                   `json:"source_ips"`
+	LocalDestinationIPs       []string                   `json:"local_destination_ips"`
+	RuleNames                 []string                   `json:"rule_names"`
+	LogSourceNames            []string                   `json:"log_source_names"`
+	OffenseTypeName           string                     `json:"offense_type_name"`
+	ClosingReason             string                     `json:"closing_reason,omitempty"`
+	Notes                     []*Note                    `json:"notes"`
+}
+
+// EnrichmentOptions are the options of an offense enricher.
+type EnrichmentOptions struct {
+	// Concurrency is the maximum number of concurrent requests per offense. Default is 5.
+	Concurrency int
+
+	// SkipNotes does not retrieve the notes.
+	SkipNotes bool
+}
+
+// OffenseEnricher resolves the references of the offenses. The addresses,
+// rules, offense types and closing reasons are cached for the lifetime of the
+// enricher, the notes are not.
+type OffenseEnricher struct {
+	siem      SIEM
+	analytics Analytics
+	opts      EnrichmentOptions
+
+	mu                        sync.Mutex
+	sourceAddresses           map[int]*SourceAddress
+	localDestinationAddresses map[int]*LocalDestinationAddress
+	ruleNames                 map[int]string
+	offenseTypes              map[int]*OffenseType
+	closingReasons            map[int]*OffenseClosingReason
+}
+
+//------------------------------------------------------------------------------
+// Factory
+//------------------------------------------------------------------------------
+
+// NewOffenseEnricher returns a new offense enricher.
+func NewOffenseEnricher(siem SIEM, analytics Analytics, opts *EnrichmentOptions) *OffenseEnricher {
+	if opts == nil {
+		opts = &EnrichmentOptions{}
+	}
+
+	e := &OffenseEnricher{
+		siem:                      siem,
+		analytics:                 analytics,
+		opts:                      *opts,
+		sourceAddresses:           map[int]*SourceAddress{},
+		localDestinationAddresses: map[int]*LocalDestinationAddress{},
+		ruleNames:                 map[int]string{},
+		offenseTypes:              map[int]*OffenseType{},
+		closingReasons:            map[int]*OffenseClosingReason{},
+	}
+	if e.opts.Concurrency <= 0 {
+		e.opts.Concurrency = defaultEnrichmentConcurrency
+	}
+
+	return e
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// GetEnrichedOffense retrieves the offense by given ID and resolves its references.
+func (e *OffenseEnricher) GetEnrichedOffense(ctx context.Context, id int) (*EnrichedOffense, error) {
+	offense, err := e.siem.GetOffense(ctx, id, "")
+	if err != nil {
+		return nil, fmt.Errorf("error while retrieving the offense: %s", err)
+	}
+
+	return e.Enrich(ctx, offense)
+}
+
+// Enrich resolves the references of the offense concurrently.
+func (e *OffenseEnricher) Enrich(ctx context.Context, offense *Offense) (*EnrichedOffense, error) {
+	enriched := &EnrichedOffense{
+		Offense:                   offense,
+		SourceAddresses:           make([]*SourceAddress, len(offense.SourceAddressIds)),
+		LocalDestinationAddresses: make([]*LocalDestinationAddress, len(offense.LocalDestinationAddressIds)),
+		SourceIPs:                 []string{},
+		LocalDestinationIPs:       []string{},
+		RuleNames:                 []string{},
+		LogSourceNames:            []string{},
+		Notes:                     []*Note{},
+	}
+	for _, logSource := range offense.LogSources {
+		enriched.LogSourceNames = append(enriched.LogSourceNames, logSource.Name)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	semaphore := make(chan struct{}, e.opts.Concurrency)
+	run := func(name string, fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+
+			if err := fn(); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("error while retrieving the %s: %s", name, err)
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}()
+	}
+
+	for i, id := range offense.SourceAddressIds {
+		i, id := i, id
+		run("source address "+strconv.Itoa(id), func() (err error) {
+			enriched.SourceAddresses[i], err = e.sourceAddress(ctx, id)
+			return err
+		})
+	}
+	for i, id := range offense.LocalDestinationAddressIds {
+		i, id := i, id
+		run("local destination address "+strconv.Itoa(id), func() (err error) {
+			enriched.LocalDestinationAddresses[i], err = e.localDestinationAddress(ctx, id)
+			return err
+		})
+	}
+	if len(offense.Rules) > 0 {
+		run("rules", func() (err error) {
+			enriched.RuleNames, err = e.rules(ctx, offense.Rules)
+			return err
+		})
+	}
+	run("offense type", func() error {
+		offenseType, err := e.offenseType(ctx, offense.OffenseType)
+		if err != nil {
+			return err
+		}
+		enriched.OffenseTypeName = offenseType.Name
+		return nil
+	})
+	if offense.ClosingReasonID != 0 {
+		run("closing reason", func() error {
+			reason, err := e.closingReason(ctx, offense.ClosingReasonID)
+			if err != nil {
+				return err
+			}
+			enriched.ClosingReason = reason.Text
+			return nil
+		})
+	}
+	if !e.opts.SkipNotes {
+		run("notes", func() error {
+			notes, err := listAllOffenseNotes(ctx, e.siem, offense.ID)
+			if err != nil {
+				return err
+			}
+			enriched.Notes = notes
+			return nil
+		})
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, address := range enriched.SourceAddresses {
+		enriched.SourceIPs = append(enriched.SourceIPs, address.SourceIP)
+	}
+	for _, address := range enriched.LocalDestinationAddresses {
+		enriched.LocalDestinationIPs = append(enriched.LocalDestinationIPs, address.LocalDestinationIP)
+	}
+
+	return enriched, nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+func (e *OffenseEnricher) sourceAddress(ctx context.Context, id int) (*SourceAddress, error) {
+	e.mu.Lock()
+	address, ok := e.sourceAddresses[id]
+	e.mu.Unlock()
+	if ok {
+		return address, nil
+	}
+
+	address, err := e.siem.GetSourceAddress(ctx, id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	e.sourceAddresses[id] = address
+	e.mu.Unlock()
+
+	return address, nil
+}
+
+func (e *OffenseEnricher) localDestinationAddress(ctx context.Context, id int) (*LocalDestinationAddress, error) {
+	e.mu.Lock()
+	address, ok := e.localDestinationAddresses[id]
+	e.mu.Unlock()
+	if ok {
+		return address, nil
+	}
+
+	address, err := e.siem.GetLocalDestinationAddress(ctx, id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	e.localDestinationAddresses[id] = address
+	e.mu.Unlock()
+
+	return address, nil
+}
+
+// rules returns the names of the rules, retrieving the missing ones in a
+// single request. The deleted rules are skipped.
+func (e *OffenseEnricher) rules(ctx context.Context, rules []Rules) ([]string, error) {
+	missing := []string{}
+	e.mu.Lock()
+	for _, rule := range rules {
+		if _, ok := e.ruleNames[rule.ID]; !ok {
+			missing = append(missing, "id = "+strconv.Itoa(rule.ID))
+		}
+	}
+	e.mu.Unlock()
+
+	if len(missing) > 0 {
+		response, err := e.analytics.ListRules(ctx, "id,name", strings.Join(missing, " or "), 0, len(missing)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		e.mu.Lock()
+		for _, rule := range response.Rules {
+			e.ruleNames[rule.ID] = rule.Name
+		}
+		e.mu.Unlock()
+	}
+
+	names := make([]string, 0, len(rules))
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, rule := range rules {
+		if name, ok := e.ruleNames[rule.ID]; ok {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+func (e *OffenseEnricher) offenseType(ctx context.Context, id int) (*OffenseType, error) {
+	e.mu.Lock()
+	offenseType, ok := e.offenseTypes[id]
+	e.mu.Unlock()
+	if ok {
+		return offenseType, nil
+	}
+
+	offenseType, err := e.siem.GetOffenseType(ctx, strconv.Itoa(id), "")
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	e.offenseTypes[id] = offenseType
+	e.mu.Unlock()
+
+	return offenseType, nil
+}
+
+func (e *OffenseEnricher) closingReason(ctx context.Context, id int) (*OffenseClosingReason, error) {
+	e.mu.Lock()
+	reason, ok := e.closingReasons[id]
+	e.mu.Unlock()
+	if ok {
+		return reason, nil
+	}
+
+	reason, err := e.siem.GetOffenseClosingReason(ctx, id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	e.closingReasons[id] = reason
+	e.mu.Unlock()
+
+	return reason, nil
+}
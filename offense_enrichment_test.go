@@ -0,0 +1,92 @@
+package goqradar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestOffenseEnricher(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+	)
+	responses := map[string]string{
+		"/api/siem/offenses/42":                   `{"id":42,"offense_type":3,"closing_reason_id":2,"source_address_ids":[7],"local_destination_address_ids":[8],"rules":[{"id":100},{"id":101}],"log_sources":[{"name":"firewall"}]}`,
+		"/api/siem/source_addresses/7":            `{"id":7,"source_ip":"10.0.0.7"}`,
+		"/api/siem/local_destination_addresses/8": `{"id":8,"local_destination_ip":"10.0.0.8"}`,
+		"/api/analytics/rules":                    `[{"id":100,"name":"Brute force"},{"id":101,"name":"Login failures"}]`,
+		"/api/siem/offense_types/3":               `{"id":3,"name":"Source IP"}`,
+		"/api/siem/offense_closing_reasons/2":     `{"id":2,"text":"False-Positive"}`,
+		"/api/siem/offenses/42/notes":             `[{"id":1,"note_text":"checked"}]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Range", "items 0-1/2")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	enricher := NewOffenseEnricher(client.SIEM, client.Analytics, nil)
+
+	for i := 0; i < 2; i++ {
+		enriched, err := enricher.GetEnrichedOffense(context.Background(), 42)
+		if err != nil {
+			t.Fatalf("should not error but error is: %s", err)
+		}
+
+		if enriched.ID != 42 || enriched.OffenseTypeName != "Source IP" || enriched.ClosingReason != "False-Positive" {
+			t.Fatalf("unexpected offense: %+v", enriched)
+		}
+		if len(enriched.SourceIPs) != 1 || enriched.SourceIPs[0] != "10.0.0.7" || enriched.LocalDestinationIPs[0] != "10.0.0.8" {
+			t.Fatalf("unexpected addresses: %v %v", enriched.SourceIPs, enriched.LocalDestinationIPs)
+		}
+		if len(enriched.RuleNames) != 2 || enriched.RuleNames[1] != "Login failures" || enriched.LogSourceNames[0] != "firewall" {
+			t.Fatalf("unexpected rules: %v", enriched.RuleNames)
+		}
+		if len(enriched.Notes) != 1 {
+			t.Fatalf("unexpected notes: %v", enriched.Notes)
+		}
+	}
+
+	// The references are cached, the notes are not
+	if requests["/api/analytics/rules"] != 1 || requests["/api/siem/source_addresses/7"] != 1 || requests["/api/siem/offenses/42/notes"] != 2 {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+}
+
+func TestOffenseEnricherClosesBodies(t *testing.T) {
+	responses := map[string]string{
+		"/api/siem/offenses/42":                   `{"id":42,"offense_type":3,"source_address_ids":[7],"local_destination_address_ids":[8],"rules":[{"id":100}]}`,
+		"/api/siem/source_addresses/7":            `{"id":7,"source_ip":"10.0.0.7"}`,
+		"/api/siem/local_destination_addresses/8": `{"id":8,"local_destination_ip":"10.0.0.8"}`,
+		"/api/analytics/rules":                    `[{"id":100,"name":"Brute force"}]`,
+		"/api/siem/offense_types/3":               `{"id":3,"name":"Source IP"}`,
+		"/api/siem/offenses/42/notes":             `[]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "items */0")
+		w.Write([]byte(responses[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	tracker := &bodyTracker{}
+	client := NewClient(&http.Client{Transport: tracker}, server.URL, "token")
+	if _, err := NewOffenseEnricher(client.SIEM, client.Analytics, nil).GetEnrichedOffense(context.Background(), 42); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if n := tracker.unclosed(); n != 0 {
+		t.Fatalf("%d response bodies are not closed", n)
+	}
+}
@@ -0,0 +1,196 @@
+package goqradar
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+const defaultOffenseSearchLimit = 1000
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// OffenseSearchOptions are the options of the search of the events or the
+// flows of an offense.
+type OffenseSearchOptions struct {
+	// Columns are the selected columns. Default is the columns of
+	// ContributingEvent or ContributingFlow.
+	Columns []AQLExpr
+
+	// Where are additional conditions.
+	Where []AQLCondition
+
+	// Limit is the maximum number of rows. Default is 1000.
+	Limit int
+
+	// RunOptions are the options of the run of the search.
+	RunOptions *RunOptions
+}
+
+// ContributingEvent is an event of an offense.
+type ContributingEvent struct {
+	StartTime       time.Time `aql:"starttime"`
+	QID             int       `aql:"qid"`
+	QIDName         string    `aql:"qid_name"`
+	LogSourceID     int       `aql:"logsourceid"`
+	LogSourceName   string    `aql:"log_source_name"`
+	Category        int       `aql:"category"`
+	CategoryName    string    `aql:"category_name"`
+	SourceIP        net.IP    `aql:"sourceip"`
+	SourcePort      int       `aql:"sourceport"`
+	DestinationIP   net.IP    `aql:"destinationip"`
+	DestinationPort int       `aql:"destinationport"`
+	Username        string    `aql:"username"`
+	Magnitude       int       `aql:"magnitude"`
+
+	// Row contains all the columns, including the custom ones.
+	Row map[string]interface{}
+}
+
+// ContributingFlow is a flow of an offense.
+type ContributingFlow struct {
+	StartTime          time.Time `aql:"starttime"`
+	LastPacketTime     time.Time `aql:"lastpackettime"`
+	QID                int       `aql:"qid"`
+	QIDName            string    `aql:"qid_name"`
+	Category           int       `aql:"category"`
+	CategoryName       string    `aql:"category_name"`
+	SourceIP           net.IP    `aql:"sourceip"`
+	SourcePort         int       `aql:"sourceport"`
+	DestinationIP      net.IP    `aql:"destinationip"`
+	DestinationPort    int       `aql:"destinationport"`
+	ProtocolID         int       `aql:"protocolid"`
+	ProtocolName       string    `aql:"protocol_name"`
+	ApplicationName    string    `aql:"application_name"`
+	SourceBytes        int       `aql:"sourcebytes"`
+	DestinationBytes   int       `aql:"destinationbytes"`
+	SourcePackets      int       `aql:"sourcepackets"`
+	DestinationPackets int       `aql:"destinationpackets"`
+
+	// Row contains all the columns, including the custom ones.
+	Row map[string]interface{}
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// OffenseEvents returns the events of the offense, between its start time and
+// its last update, with the QID, log source and category names.
+func (endpoint *Endpoint) OffenseEvents(ctx context.Context, offenseID int, opts *OffenseSearchOptions) ([]*ContributingEvent, error) {
+	columns := []AQLExpr{
+		Col("starttime"),
+		Col("qid"),
+		QIDName(Col("qid")).As("qid_name"),
+		Col("logsourceid"),
+		LogSourceName(Col("logsourceid")).As("log_source_name"),
+		Col("category"),
+		CategoryName(Col("category")).As("category_name"),
+		Col("sourceip"),
+		Col("sourceport"),
+		Col("destinationip"),
+		Col("destinationport"),
+		Col("username"),
+		Col("magnitude"),
+	}
+
+	result, err := endpoint.searchOffense(ctx, offenseID, DatabaseEvents, columns, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []*ContributingEvent{}
+	if _, err := result.Decode(&events); err != nil {
+		return nil, err
+	}
+	for i, event := range events {
+		event.Row = result.Rows[i]
+	}
+
+	return events, nil
+}
+
+// OffenseFlows returns the flows of the offense, between its start time and
+// its last update, with the QID, category, protocol and application names.
+func (endpoint *Endpoint) OffenseFlows(ctx context.Context, offenseID int, opts *OffenseSearchOptions) ([]*ContributingFlow, error) {
+	columns := []AQLExpr{
+		Col("starttime"),
+		Col("lastpackettime"),
+		Col("qid"),
+		QIDName(Col("qid")).As("qid_name"),
+		Col("category"),
+		CategoryName(Col("category")).As("category_name"),
+		Col("sourceip"),
+		Col("sourceport"),
+		Col("destinationip"),
+		Col("destinationport"),
+		Col("protocolid"),
+		Func("PROTOCOLNAME", Col("protocolid")).As("protocol_name"),
+		Func("APPLICATIONNAME", Col("applicationid")).As("application_name"),
+		Col("sourcebytes"),
+		Col("destinationbytes"),
+		Col("sourcepackets"),
+		Col("destinationpackets"),
+	}
+
+	result, err := endpoint.searchOffense(ctx, offenseID, DatabaseFlows, columns, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	flows := []*ContributingFlow{}
+	if _, err := result.Decode(&flows); err != nil {
+		return nil, err
+	}
+	for i, flow := range flows {
+		flow.Row = result.Rows[i]
+	}
+
+	return flows, nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// searchOffense runs the INOFFENSE query of the offense over its window.
+func (endpoint *Endpoint) searchOffense(ctx context.Context, offenseID int, database string, columns []AQLExpr, opts *OffenseSearchOptions) (*ArielResult, error) {
+	if opts == nil {
+		opts = &OffenseSearchOptions{}
+	}
+	if len(opts.Columns) > 0 {
+		columns = opts.Columns
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultOffenseSearchLimit
+	}
+
+	// Retrieve the window of the offense
+	offense, err := endpoint.GetOffense(ctx, offenseID, "id,start_time,last_updated_time")
+	if err != nil {
+		return nil, fmt.Errorf("error while retrieving the offense: %s", err)
+	}
+	// The STOP time is excluded, the events at the last update time are included
+	start := time.Unix(0, int64(offense.StartTime)*int64(time.Millisecond))
+	stop := time.Unix(0, int64(offense.LastUpdatedTime)*int64(time.Millisecond)).Add(time.Millisecond)
+	if !stop.After(start) {
+		stop = start.Add(time.Millisecond)
+	}
+
+	aql, err := Select(columns...).
+		From(database).
+		Where(append([]AQLCondition{InOffense(offenseID)}, opts.Where...)...).
+		OrderBy(Col("starttime")).
+		Limit(limit).
+		Between(start, stop).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoint.Run(ctx, aql, opts.RunOptions)
+}
@@ -0,0 +1,48 @@
+package goqradar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOffenseEvents(t *testing.T) {
+	var aql string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/siem/offenses/42":
+			w.Write([]byte(`{"id":42,"start_time":1600000000000,"last_updated_time":1600003600000}`))
+		case "/api/ariel/searches/":
+			aql = r.URL.Query().Get("queryExpression")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(&Searches{SearchID: "s1", Status: SearchStatusCompleted, RecordCount: 1})
+		case "/api/ariel/searches/s1":
+			json.NewEncoder(w).Encode(&Searches{SearchID: "s1", Status: SearchStatusCompleted, RecordCount: 1})
+		case "/api/ariel/searches/s1/results":
+			w.Write([]byte(`{"events":[{"starttime":1600000001000,"qid_name":"Login Failed","log_source_name":"sshd","sourceip":"10.0.0.1","username":null,"host":"web01"}]}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	events, err := client.SIEM.OffenseEvents(context.Background(), 42, &OffenseSearchOptions{
+		Columns: []AQLExpr{Col("starttime"), QIDName(Col("qid")).As("qid_name"), LogSourceName(Col("logsourceid")).As("log_source_name"), Col("sourceip"), Col("username"), Col("host")},
+		Limit:   10,
+	})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	expected := `SELECT starttime, QIDNAME(qid) AS qid_name, LOGSOURCENAME(logsourceid) AS log_source_name, sourceip, username, host FROM events WHERE INOFFENSE(42) ORDER BY starttime ASC LIMIT 10 START 1600000000000 STOP 1600003600001`
+	if aql != expected {
+		t.Fatalf("unexpected query:\n%s\n%s", aql, expected)
+	}
+	if len(events) != 1 || events[0].QIDName != "Login Failed" || events[0].SourceIP.String() != "10.0.0.1" || events[0].Row["host"] != "web01" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
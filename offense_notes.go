@@ -0,0 +1,129 @@
+package goqradar
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const defaultNotesPageSize = 50
+
+var structuredNoteHeaderPattern = regexp.MustCompile(`^\[([^\]]+)\] (.*)$`)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// StructuredNote is a note written with a consistent template, such as the
+// record of a playbook step:
+//
+//	[Playbook] Isolate the host
+//	actor: soar
+//	status: done
+//
+//	The host was isolated by the EDR.
+type StructuredNote struct {
+	// Kind is the kind of the record, such as "Playbook".
+	Kind string
+
+	// Title is the first line, after the kind.
+	Title string
+
+	// Fields are written one per line, sorted by key.
+	Fields map[string]string
+
+	// Text is the free text after the fields.
+	Text string
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// String renders the note. The line breaks of the title, the keys and the
+// values are replaced by spaces, so that the note can be parsed back.
+func (n *StructuredNote) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[%s] %s\n", singleLine(strings.Replace(n.Kind, "]", ")", -1)), singleLine(n.Title))
+
+	keys := make([]string, 0, len(n.Fields))
+	for key := range n.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", singleLine(strings.Replace(key, ":", "_", -1)), singleLine(n.Fields[key]))
+	}
+
+	if n.Text != "" {
+		b.WriteString("\n")
+		b.WriteString(n.Text)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// ParseStructuredNote parses a note written by StructuredNote. It returns
+// false if the note does not follow the template.
+func ParseStructuredNote(text string) (*StructuredNote, bool) {
+	lines := strings.Split(text, "\n")
+	match := structuredNoteHeaderPattern.FindStringSubmatch(lines[0])
+	if match == nil {
+		return nil, false
+	}
+
+	n := &StructuredNote{
+		Kind:   match[1],
+		Title:  match[2],
+		Fields: map[string]string{},
+	}
+	for i := 1; i < len(lines); i++ {
+		if lines[i] == "" {
+			n.Text = strings.Join(lines[i+1:], "\n")
+			break
+		}
+
+		parts := strings.SplitN(lines[i], ": ", 2)
+		if len(parts) != 2 {
+			return nil, false
+		}
+		n.Fields[parts[0]] = parts[1]
+	}
+
+	return n, true
+}
+
+// AppendStructuredNote creates a structured note on the given offense.
+func (endpoint *Endpoint) AppendStructuredNote(ctx context.Context, offenseID int, note *StructuredNote) (*Note, error) {
+	if note.Kind == "" || note.Title == "" {
+		return nil, fmt.Errorf("the kind and the title of the note are required")
+	}
+
+	return endpoint.CreateOffenseNote(ctx, offenseID, note.String(), "")
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// listAllOffenseNotes returns all the notes of the offense, page by page.
+func listAllOffenseNotes(ctx context.Context, siem SIEM, offenseID int) ([]*Note, error) {
+	notes := []*Note{}
+	for min := 0; ; min += defaultNotesPageSize {
+		page, err := siem.ListOffenseNotes(ctx, offenseID, "", "", min, min+defaultNotesPageSize-1)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, page.Notes...)
+		if len(page.Notes) < defaultNotesPageSize || min+defaultNotesPageSize >= page.Total {
+			return notes, nil
+		}
+	}
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
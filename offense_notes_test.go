@@ -0,0 +1,96 @@
+package goqradar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateOffenseNoteEncoding(t *testing.T) {
+	var noteText, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		noteText = r.URL.Query().Get("note_text")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	_, err := client.SIEM.AppendStructuredNote(context.Background(), 42, &StructuredNote{
+		Kind:   "Playbook",
+		Title:  "Isolate the host #1",
+		Fields: map[string]string{"status": "done", "actor": "soar & edr"},
+		Text:   "Isolated.\nTicket INC-1",
+	})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	if path != "/api/siem/offenses/42/notes" {
+		t.Fatalf("unexpected path: %s", path)
+	}
+	expected := "[Playbook] Isolate the host #1\nactor: soar & edr\nstatus: done\n\nIsolated.\nTicket INC-1"
+	if noteText != expected {
+		t.Fatalf("unexpected note:\n%q\n%q", noteText, expected)
+	}
+
+	note, ok := ParseStructuredNote(noteText)
+	if !ok || note.Kind != "Playbook" || note.Fields["actor"] != "soar & edr" || note.Text != "Isolated.\nTicket INC-1" {
+		t.Fatalf("unexpected parsed note: %+v", note)
+	}
+	if _, ok := ParseStructuredNote("free text"); ok {
+		t.Fatal("should not parse a free text note")
+	}
+}
+
+func TestStructuredNoteRoundTrip(t *testing.T) {
+	note := &StructuredNote{
+		Kind:   "Play]book",
+		Title:  "Isolate\nthe host",
+		Fields: map[string]string{"status": "done: partially", "step:id": "3", "comment": "line 1\r\nline 2", "empty": ""},
+		Text:   "\nFirst line\n\nafter a blank line",
+	}
+
+	parsed, ok := ParseStructuredNote(note.String())
+	if !ok {
+		t.Fatalf("should parse the rendered note:\n%s", note.String())
+	}
+	if parsed.Kind != "Play)book" || parsed.Title != "Isolate the host" || parsed.Text != note.Text {
+		t.Fatalf("unexpected note: %+v", parsed)
+	}
+	expected := map[string]string{"status": "done: partially", "step_id": "3", "comment": "line 1 line 2", "empty": ""}
+	if len(parsed.Fields) != len(expected) {
+		t.Fatalf("unexpected fields: %v", parsed.Fields)
+	}
+	for key, value := range expected {
+		if parsed.Fields[key] != value {
+			t.Fatalf("unexpected fields: %v", parsed.Fields)
+		}
+	}
+}
+
+func TestParseStructuredNoteMalformed(t *testing.T) {
+	malformed := []string{
+		"",
+		"\n",
+		"[] Empty kind",
+		"[Playbook]",
+		"[Playbook]Title",
+		"Playbook] Title",
+		"[Playbook] Title\nstatus done",
+		"[Playbook] Title\nstatus: done\nnot a field",
+	}
+	for _, text := range malformed {
+		if note, ok := ParseStructuredNote(text); ok || note != nil {
+			t.Fatalf("should not parse %q: %+v", text, note)
+		}
+	}
+
+	// A note without fields nor text is valid
+	note, ok := ParseStructuredNote("[Playbook] Title")
+	if !ok || note.Title != "Title" || len(note.Fields) != 0 || note.Text != "" {
+		t.Fatalf("unexpected note: %+v", note)
+	}
+}
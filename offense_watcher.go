@@ -0,0 +1,389 @@
+package goqradar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatcherInterval       = 30 * time.Second
+	defaultWatcherPageSize       = 50
+	defaultWatcherStateRetention = 7 * 24 * time.Hour
+)
+
+// Types of the offense events.
+const (
+	OffenseCreated          = "CREATED"
+	OffenseUpdated          = "UPDATED"
+	OffenseClosed           = "CLOSED"
+	OffenseReassigned       = "REASSIGNED"
+	OffenseMagnitudeChanged = "MAGNITUDE_CHANGED"
+)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// OffenseEvent is a change of an offense. An update can emit several events,
+// such as closed and reassigned.
+type OffenseEvent struct {
+	Type    string
+	Offense *Offense
+
+	// Previous is the state of the offense when it was last seen, or nil.
+	Previous *OffenseState
+}
+
+// OffenseState is the state of an offense when it was last seen.
+type OffenseState struct {
+	LastUpdatedTime int    `json:"last_updated_time"`
+	Status          string `json:"status"`
+	AssignedTo      string `json:"assigned_to"`
+	Magnitude       int    `json:"magnitude"`
+}
+
+// OffenseCheckpoint is the position of an offense watcher.
+type OffenseCheckpoint struct {
+	// LastUpdatedTime is the greatest last_updated_time seen, in milliseconds.
+	LastUpdatedTime int `json:"last_updated_time"`
+
+	// Boundary are the IDs of the offenses seen at LastUpdatedTime, which are
+	// fetched again by the next poll and skipped.
+	Boundary []int `json:"boundary"`
+
+	// Offenses are the states of the offenses updated within the state
+	// retention, by ID.
+	Offenses map[int]*OffenseState `json:"offenses"`
+}
+
+// OffenseCheckpointStore stores the checkpoint of an offense watcher.
+type OffenseCheckpointStore interface {
+	// Load returns the checkpoint, or nil if there is none.
+	Load() (*OffenseCheckpoint, error)
+	Save(*OffenseCheckpoint) error
+}
+
+// OffenseWatcherOptions are the options of an offense watcher.
+type OffenseWatcherOptions struct {
+	// Interval is the time between two polls. Default is 30 seconds.
+	Interval time.Duration
+
+	// PageSize is the number of offenses fetched per request. Default is 50.
+	PageSize int
+
+	// StateRetention is how long the state of an offense is kept after its
+	// last update. The changes of an offense without state are emitted as if
+	// it was seen for the first time. Default is 7 days.
+	StateRetention time.Duration
+
+	// Filter is an additional filter of the offenses, such as "domain_id = 1".
+	Filter string
+
+	// Since is the first position, when there is no checkpoint. Default is the
+	// current time, so that only the next changes are emitted.
+	Since time.Time
+
+	// Store stores the checkpoint after every poll. Default is in memory.
+	Store OffenseCheckpointStore
+}
+
+// OffenseWatcher polls the offenses updated since the checkpoint and emits
+// their changes.
+type OffenseWatcher struct {
+	siem SIEM
+	opts OffenseWatcherOptions
+
+	checkpoint *OffenseCheckpoint
+}
+
+// FileCheckpointStore stores the checkpoint in a JSON file.
+type FileCheckpointStore struct {
+	path string
+}
+
+// MemoryCheckpointStore stores the checkpoint in memory.
+type MemoryCheckpointStore struct {
+	mu         sync.Mutex
+	checkpoint *OffenseCheckpoint
+}
+
+//------------------------------------------------------------------------------
+// Factory
+//------------------------------------------------------------------------------
+
+// NewOffenseWatcher returns a new offense watcher, restored from the checkpoint of the store.
+func NewOffenseWatcher(siem SIEM, opts *OffenseWatcherOptions) (*OffenseWatcher, error) {
+	if opts == nil {
+		opts = &OffenseWatcherOptions{}
+	}
+
+	w := &OffenseWatcher{
+		siem: siem,
+		opts: *opts,
+	}
+	if w.opts.Interval <= 0 {
+		w.opts.Interval = defaultWatcherInterval
+	}
+	if w.opts.PageSize <= 0 {
+		w.opts.PageSize = defaultWatcherPageSize
+	}
+	if w.opts.StateRetention <= 0 {
+		w.opts.StateRetention = defaultWatcherStateRetention
+	}
+	if w.opts.Store == nil {
+		w.opts.Store = NewMemoryCheckpointStore()
+	}
+
+	// Restore the checkpoint
+	checkpoint, err := w.opts.Store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("error while loading the checkpoint: %s", err)
+	}
+	if checkpoint == nil {
+		since := w.opts.Since
+		if since.IsZero() {
+			since = time.Now()
+		}
+		checkpoint = &OffenseCheckpoint{LastUpdatedTime: int(epochMillis(since))}
+	}
+	if checkpoint.Offenses == nil {
+		checkpoint.Offenses = map[int]*OffenseState{}
+	}
+	w.checkpoint = checkpoint
+
+	return w, nil
+}
+
+// NewFileCheckpointStore returns a new store of the checkpoint in the file.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// NewMemoryCheckpointStore returns a new store of the checkpoint in memory.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{}
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// Run polls the offenses until the context is canceled, and sends the events
+// on the channel. It does not close the channel.
+func (w *OffenseWatcher) Run(ctx context.Context, events chan<- *OffenseEvent) error {
+	for {
+		if err := w.poll(ctx, events); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.opts.Interval):
+		}
+	}
+}
+
+// Checkpoint returns the greatest last_updated_time seen.
+func (w *OffenseWatcher) Checkpoint() time.Time {
+	return time.Unix(0, int64(w.checkpoint.LastUpdatedTime)*int64(time.Millisecond))
+}
+
+// Load reads the checkpoint from the file.
+func (s *FileCheckpointStore) Load() (*OffenseCheckpoint, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while reading the checkpoint: %s", err)
+	}
+
+	var checkpoint *OffenseCheckpoint
+	err = json.Unmarshal(b, &checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("error while unmarshalling the checkpoint: %s", err)
+	}
+
+	return checkpoint, nil
+}
+
+// Save writes the checkpoint into the file.
+func (s *FileCheckpointStore) Save(checkpoint *OffenseCheckpoint) error {
+	if err := writeJSONFile(s.path, checkpoint); err != nil {
+		return fmt.Errorf("error while writing the checkpoint: %s", err)
+	}
+
+	return nil
+}
+
+// Load returns the checkpoint.
+func (s *MemoryCheckpointStore) Load() (*OffenseCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.checkpoint, nil
+}
+
+// Save keeps the checkpoint.
+func (s *MemoryCheckpointStore) Save(checkpoint *OffenseCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoint = checkpoint
+
+	return nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// poll fetches the offenses updated since the checkpoint. The offenses are
+// sorted by last_updated_time then ID, and every page starts after the last
+// offense of the previous one, so that an offense updated during the poll
+// does not shift the pages. The offenses of the boundary of the checkpoint
+// are fetched again, and skipped.
+func (w *OffenseWatcher) poll(ctx context.Context, events chan<- *OffenseEvent) error {
+	since := w.checkpoint.LastUpdatedTime
+	boundary := map[int]bool{}
+	for _, id := range w.checkpoint.Boundary {
+		boundary[id] = true
+	}
+
+	changed := false
+	position := "last_updated_time >= " + strconv.Itoa(since)
+	for {
+		filter := position
+		if w.opts.Filter != "" {
+			filter = "(" + w.opts.Filter + ") and " + filter
+		}
+
+		page, err := w.siem.ListOffenses(ctx, "", filter, "+last_updated_time,+id", 0, w.opts.PageSize-1)
+		if err != nil {
+			return fmt.Errorf("error while listing the offenses: %s", err)
+		}
+
+		for _, offense := range page.Offenses {
+			if offense.LastUpdatedTime == since && boundary[offense.ID] {
+				continue
+			}
+
+			for _, event := range w.diff(offense, since) {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			w.update(offense)
+			changed = true
+		}
+
+		if len(page.Offenses) < w.opts.PageSize {
+			break
+		}
+
+		// The next page starts after the last offense
+		last := page.Offenses[len(page.Offenses)-1]
+		position = fmt.Sprintf("(last_updated_time > %d or (last_updated_time = %d and id > %d))", last.LastUpdatedTime, last.LastUpdatedTime, last.ID)
+	}
+
+	if w.pruneStates() {
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := w.opts.Store.Save(w.checkpoint); err != nil {
+		return fmt.Errorf("error while saving the checkpoint: %s", err)
+	}
+
+	return nil
+}
+
+// update records the state of the offense and moves the checkpoint.
+func (w *OffenseWatcher) update(offense *Offense) {
+	w.checkpoint.Offenses[offense.ID] = &OffenseState{
+		LastUpdatedTime: offense.LastUpdatedTime,
+		Status:          offense.Status,
+		AssignedTo:      offense.AssignedTo,
+		Magnitude:       offense.Magnitude,
+	}
+
+	switch {
+	case offense.LastUpdatedTime > w.checkpoint.LastUpdatedTime:
+		w.checkpoint.LastUpdatedTime = offense.LastUpdatedTime
+		w.checkpoint.Boundary = []int{offense.ID}
+	case offense.LastUpdatedTime == w.checkpoint.LastUpdatedTime:
+		for _, id := range w.checkpoint.Boundary {
+			if id == offense.ID {
+				return
+			}
+		}
+		w.checkpoint.Boundary = append(w.checkpoint.Boundary, offense.ID)
+	}
+}
+
+// pruneStates removes the states of the offenses which were not updated
+// within the retention. It returns true if a state was removed.
+func (w *OffenseWatcher) pruneStates() bool {
+	cutoff := w.checkpoint.LastUpdatedTime - int(w.opts.StateRetention/time.Millisecond)
+
+	pruned := false
+	for id, state := range w.checkpoint.Offenses {
+		if state.LastUpdatedTime < cutoff {
+			delete(w.checkpoint.Offenses, id)
+			pruned = true
+		}
+	}
+
+	return pruned
+}
+
+// diff returns the events of the offense since its previous state.
+func (w *OffenseWatcher) diff(offense *Offense, since int) []*OffenseEvent {
+	previous := w.checkpoint.Offenses[offense.ID]
+
+	// Already seen at the boundary
+	if previous != nil && previous.LastUpdatedTime >= offense.LastUpdatedTime {
+		return nil
+	}
+
+	event := func(kind string) *OffenseEvent {
+		return &OffenseEvent{Type: kind, Offense: offense, Previous: previous}
+	}
+
+	if previous == nil {
+		if offense.FirstPersistedTime >= since || offense.StartTime >= since {
+			return []*OffenseEvent{event(OffenseCreated)}
+		}
+		if offense.Status == OffenseStatusClosed {
+			return []*OffenseEvent{event(OffenseClosed)}
+		}
+		return []*OffenseEvent{event(OffenseUpdated)}
+	}
+
+	events := []*OffenseEvent{}
+	if offense.Status == OffenseStatusClosed && previous.Status != OffenseStatusClosed {
+		events = append(events, event(OffenseClosed))
+	}
+	if offense.AssignedTo != previous.AssignedTo {
+		events = append(events, event(OffenseReassigned))
+	}
+	if offense.Magnitude != previous.Magnitude {
+		events = append(events, event(OffenseMagnitudeChanged))
+	}
+	if len(events) == 0 {
+		events = append(events, event(OffenseUpdated))
+	}
+
+	return events
+}
@@ -0,0 +1,127 @@
+package goqradar
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubSIEM overrides ListOffenses of the SIEM endpoint.
+type stubSIEM struct {
+	SIEM
+	listOffenses func(filter string, min, max int) (*OffensePaginatedResponse, error)
+}
+
+func (s *stubSIEM) ListOffenses(ctx context.Context, fields, filter, sort string, min, max int) (*OffensePaginatedResponse, error) {
+	return s.listOffenses(filter, min, max)
+}
+
+func TestOffenseWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goqradar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	since := time.Unix(1600000000, 0)
+	ms := int(epochMillis(since))
+	pages := [][]*Offense{
+		// First poll
+		{{ID: 1, StartTime: ms + 10, LastUpdatedTime: ms + 10, Status: OffenseStatusOpen, Magnitude: 3}},
+		{{ID: 2, StartTime: ms - 1000, LastUpdatedTime: ms + 20, Status: OffenseStatusOpen, Magnitude: 5}},
+		{},
+		// Second poll, the offense 2 is seen at the boundary
+		{{ID: 2, StartTime: ms - 1000, LastUpdatedTime: ms + 20, Status: OffenseStatusOpen, Magnitude: 5}},
+		{{ID: 1, StartTime: ms + 10, LastUpdatedTime: ms + 30, Status: OffenseStatusClosed, AssignedTo: "bob", Magnitude: 4}},
+		{},
+	}
+	filters := []string{}
+	siem := &stubSIEM{listOffenses: func(filter string, min, max int) (*OffensePaginatedResponse, error) {
+		if min != 0 {
+			t.Fatalf("the pages should not be fetched by offset: %d", min)
+		}
+		filters = append(filters, filter)
+
+		page := pages[0]
+		pages = pages[1:]
+		return &OffensePaginatedResponse{Offenses: page}, nil
+	}}
+
+	store := NewFileCheckpointStore(filepath.Join(dir, "checkpoint.json"))
+	watcher, err := NewOffenseWatcher(siem, &OffenseWatcherOptions{Since: since, PageSize: 1, Store: store})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	events := make(chan *OffenseEvent, 10)
+	for i := 0; i < 2; i++ {
+		if err := watcher.poll(context.Background(), events); err != nil {
+			t.Fatalf("should not error but error is: %s", err)
+		}
+	}
+	close(events)
+
+	types := []string{}
+	for event := range events {
+		types = append(types, event.Type+":"+strconv.Itoa(event.Offense.ID))
+	}
+	expected := "CREATED:1 UPDATED:2 CLOSED:1 REASSIGNED:1 MAGNITUDE_CHANGED:1"
+	if strings.Join(types, " ") != expected {
+		t.Fatalf("unexpected events: %v", types)
+	}
+	expectedFilters := []string{
+		fmt.Sprintf("last_updated_time >= %d", ms),
+		fmt.Sprintf("(last_updated_time > %d or (last_updated_time = %d and id > 1))", ms+10, ms+10),
+		fmt.Sprintf("(last_updated_time > %d or (last_updated_time = %d and id > 2))", ms+20, ms+20),
+		fmt.Sprintf("last_updated_time >= %d", ms+20),
+		fmt.Sprintf("(last_updated_time > %d or (last_updated_time = %d and id > 2))", ms+20, ms+20),
+		fmt.Sprintf("(last_updated_time > %d or (last_updated_time = %d and id > 1))", ms+30, ms+30),
+	}
+	if strings.Join(filters, "\n") != strings.Join(expectedFilters, "\n") {
+		t.Fatalf("unexpected filters: %v", filters)
+	}
+
+	// Restore from the file
+	restored, err := NewOffenseWatcher(siem, &OffenseWatcherOptions{Store: store})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if restored.checkpoint.LastUpdatedTime != ms+30 || restored.checkpoint.Offenses[1].Status != OffenseStatusClosed {
+		t.Fatalf("unexpected checkpoint: %+v", restored.checkpoint)
+	}
+	if len(restored.checkpoint.Boundary) != 1 || restored.checkpoint.Boundary[0] != 1 {
+		t.Fatalf("unexpected boundary: %v", restored.checkpoint.Boundary)
+	}
+}
+
+func TestOffenseWatcherPruneStates(t *testing.T) {
+	watcher, err := NewOffenseWatcher(&stubSIEM{}, &OffenseWatcherOptions{StateRetention: time.Hour})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	hour := int(time.Hour / time.Millisecond)
+	watcher.checkpoint = &OffenseCheckpoint{
+		LastUpdatedTime: 10 * hour,
+		Offenses: map[int]*OffenseState{
+			1: {LastUpdatedTime: 8 * hour},
+			2: {LastUpdatedTime: 9 * hour},
+			3: {LastUpdatedTime: 10 * hour},
+		},
+	}
+	if !watcher.pruneStates() {
+		t.Fatal("a state should be pruned")
+	}
+	if len(watcher.checkpoint.Offenses) != 2 || watcher.checkpoint.Offenses[1] != nil {
+		t.Fatalf("unexpected states: %v", watcher.checkpoint.Offenses)
+	}
+	if watcher.pruneStates() {
+		t.Fatal("no state should be pruned")
+	}
+}
@@ -10,7 +10,7 @@ type options struct {
 	Endpoint string
 	Headers  *http.Header
 	Params   *url.Values
-	Data     map[string]interface{}
+	Data     interface{}
 	Result   interface{}
 }
 
@@ -40,3 +40,11 @@ func WithParam(key, value string) Option {
 		return nil
 	}
 }
+
+// WithData adds the data sent as JSON body.
+func WithData(data interface{}) Option {
+	return func(opts *options) error {
+		opts.Data = data
+		return nil
+	}
+}
@@ -0,0 +1,460 @@
+package goqradar
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of the payloads.
+const (
+	PayloadFormatLEEF = "LEEF"
+	PayloadFormatCEF  = "CEF"
+)
+
+var cefKeyPattern = regexp.MustCompile(`(?:^|\s)([A-Za-z0-9_.\[\]-]+)=`)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// Payload is a parsed event payload.
+type Payload struct {
+	// Raw is the decoded payload.
+	Raw string
+
+	// Syslog is the syslog header, if any.
+	Syslog *SyslogHeader
+
+	// Format is either "LEEF" or "CEF", or empty if the content is not structured.
+	Format string
+
+	// Version is the version of the LEEF or CEF format.
+	Version string
+
+	// Header contains the LEEF or CEF header fields, such as vendor, product,
+	// version and event_id, plus name and severity for CEF.
+	Header map[string]string
+
+	// Fields contains the LEEF attributes or the CEF extensions.
+	Fields map[string]string
+
+	// Message is the content after the syslog header.
+	Message string
+}
+
+// SyslogHeader is an RFC 5424 or RFC 3164 syslog header.
+type SyslogHeader struct {
+	// RFC is either 5424 or 3164.
+	RFC       int
+	Priority  int
+	Facility  int
+	Severity  int
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+
+	// StructuredData is the RFC 5424 structured data, by SD-ID.
+	StructuredData map[string]map[string]string
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// DecodePayload returns the payload column as a string. The payload column is
+// base64 encoded, while UTF8(payload) is already a string: the value is
+// decoded only if encoded is true.
+func DecodePayload(value interface{}, encoded bool) (string, error) {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return "", fmt.Errorf("unexpected payload type %T", value)
+	}
+
+	if !encoded {
+		return s, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 payload: %s", err)
+	}
+
+	return string(decoded), nil
+}
+
+// ParsePayload parses the syslog header and the LEEF or CEF content of the
+// payload. An RFC 3164 timestamp has no year, the year is the one which puts
+// the timestamp closest to the reference time, such as the starttime of the
+// event. The current time is used if the reference time is zero.
+func ParsePayload(raw string, reference time.Time) *Payload {
+	p := &Payload{
+		Raw:     raw,
+		Header:  map[string]string{},
+		Fields:  map[string]string{},
+		Message: raw,
+	}
+
+	if reference.IsZero() {
+		reference = time.Now()
+	}
+	p.Syslog, p.Message = parseSyslogHeader(raw, reference)
+
+	// The LEEF or CEF content can follow a syslog header without priority
+	if i := strings.Index(p.Message, "LEEF:"); i >= 0 {
+		p.parseLEEF(p.Message[i:])
+	} else if i := strings.Index(p.Message, "CEF:"); i >= 0 {
+		p.parseCEF(p.Message[i:])
+	}
+
+	return p
+}
+
+// Payloads decodes and parses the payload column of every row. The column is
+// base64 encoded if it selects the raw payload rather than UTF8(payload). The
+// rows without the column are skipped.
+func (r *ArielResult) Payloads(column string, encoded bool) ([]*Payload, error) {
+	return PayloadsFromRows(r.Rows, column, encoded)
+}
+
+// PayloadsFromRows decodes and parses the payload column of every row, such
+// as the rows returned by GetSearchesResults. The rows without the column are
+// skipped. The starttime column, if selected, is the reference time of the
+// RFC 3164 timestamps.
+func PayloadsFromRows(rows []map[string]interface{}, column string, encoded bool) ([]*Payload, error) {
+	payloads := []*Payload{}
+	for i, row := range rows {
+		value, ok := row[column]
+		if !ok || value == nil {
+			continue
+		}
+
+		raw, err := DecodePayload(value, encoded)
+		if err != nil {
+			return nil, &DecodeError{Row: i, Column: column, Err: err}
+		}
+
+		var reference time.Time
+		if starttime, ok := rowStartTime(row); ok {
+			reference = time.Unix(0, starttime*int64(time.Millisecond))
+		}
+		payloads = append(payloads, ParsePayload(raw, reference))
+	}
+
+	return payloads, nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// parseSyslogHeader parses the syslog header, and returns the message after it.
+func parseSyslogHeader(raw string, reference time.Time) (*SyslogHeader, string) {
+	if !strings.HasPrefix(raw, "<") {
+		return nil, raw
+	}
+	end := strings.IndexByte(raw, '>')
+	if end < 2 || end > 4 {
+		return nil, raw
+	}
+	priority, err := strconv.Atoi(raw[1:end])
+	if err != nil || priority > 191 {
+		return nil, raw
+	}
+
+	header := &SyslogHeader{
+		Priority: priority,
+		Facility: priority / 8,
+		Severity: priority % 8,
+	}
+	rest := raw[end+1:]
+
+	// RFC 5424
+	if strings.HasPrefix(rest, "1 ") {
+		header.RFC = 5424
+		fields := strings.SplitN(rest[2:], " ", 6)
+		if len(fields) < 5 {
+			return header, rest
+		}
+		if fields[0] != "-" {
+			header.Timestamp, _ = time.Parse(time.RFC3339Nano, fields[0])
+		}
+		header.Hostname = nilValue(fields[1])
+		header.AppName = nilValue(fields[2])
+		header.ProcID = nilValue(fields[3])
+		header.MsgID = nilValue(fields[4])
+
+		message := ""
+		if len(fields) == 6 {
+			header.StructuredData, message = parseStructuredData(fields[5])
+		}
+		return header, strings.TrimPrefix(message, "\uFEFF")
+	}
+
+	// RFC 3164
+	header.RFC = 3164
+	if len(rest) < 15 {
+		return header, rest
+	}
+	timestamp, err := time.Parse(time.Stamp, rest[:15])
+	if err != nil {
+		return header, rest
+	}
+	header.Timestamp = closestYear(timestamp, reference)
+	rest = strings.TrimPrefix(rest[15:], " ")
+
+	// Hostname
+	i := strings.IndexByte(rest, ' ')
+	if i < 0 {
+		header.Hostname = rest
+		return header, ""
+	}
+	header.Hostname, rest = rest[:i], rest[i+1:]
+
+	// Tag, with the optional process ID, which is often omitted before LEEF and CEF
+	if i := strings.IndexByte(rest, ':'); i > 0 && !strings.ContainsAny(rest[:i], " ") && rest[:i] != PayloadFormatLEEF && rest[:i] != PayloadFormatCEF {
+		tag := rest[:i]
+		if j := strings.IndexByte(tag, '['); j > 0 && strings.HasSuffix(tag, "]") {
+			header.ProcID = tag[j+1 : len(tag)-1]
+			tag = tag[:j]
+		}
+		header.AppName = tag
+		rest = strings.TrimPrefix(rest[i+1:], " ")
+	}
+
+	return header, rest
+}
+
+// closestYear returns the timestamp without year in the year which puts it
+// closest to the reference time, so that a message of December parsed in
+// January is in the previous year.
+func closestYear(timestamp, reference time.Time) time.Time {
+	var closest time.Time
+	for year := reference.Year() - 1; year <= reference.Year()+1; year++ {
+		t := time.Date(year, timestamp.Month(), timestamp.Day(), timestamp.Hour(), timestamp.Minute(), timestamp.Second(), timestamp.Nanosecond(), time.UTC)
+		if closest.IsZero() || absDuration(t.Sub(reference)) < absDuration(closest.Sub(reference)) {
+			closest = t
+		}
+	}
+
+	return closest
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+
+	return d
+}
+
+// parseStructuredData parses the RFC 5424 structured data, and returns the message after it.
+func parseStructuredData(s string) (map[string]map[string]string, string) {
+	if strings.HasPrefix(s, "- ") || s == "-" {
+		return nil, strings.TrimPrefix(s[1:], " ")
+	}
+
+	data := map[string]map[string]string{}
+	for strings.HasPrefix(s, "[") {
+		// Find the end of the element, outside of the quoted values
+		end, quoted := -1, false
+		for i := 1; i < len(s) && end < 0; i++ {
+			switch {
+			case s[i] == '\\' && quoted:
+				i++
+			case s[i] == '"':
+				quoted = !quoted
+			case s[i] == ']' && !quoted:
+				end = i
+			}
+		}
+		if end < 0 {
+			break
+		}
+
+		element := s[1:end]
+		s = s[end+1:]
+
+		id := element
+		if i := strings.IndexByte(element, ' '); i >= 0 {
+			id, element = element[:i], element[i+1:]
+		} else {
+			element = ""
+		}
+
+		params := map[string]string{}
+		for element != "" {
+			i := strings.Index(element, `="`)
+			if i < 0 {
+				break
+			}
+			name := strings.TrimSpace(element[:i])
+			element = element[i+2:]
+
+			var value strings.Builder
+			j := 0
+			for ; j < len(element) && element[j] != '"'; j++ {
+				if element[j] == '\\' && j+1 < len(element) {
+					j++
+				}
+				value.WriteByte(element[j])
+			}
+			params[name] = value.String()
+			if j < len(element) {
+				j++
+			}
+			element = element[j:]
+		}
+		data[id] = params
+	}
+
+	return data, strings.TrimPrefix(s, " ")
+}
+
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+
+	return s
+}
+
+// parseLEEF parses a LEEF 1.0 or 2.0 content.
+//
+//	LEEF:1.0|Vendor|Product|Version|EventID|key=value<tab>key=value
+//	LEEF:2.0|Vendor|Product|Version|EventID|Delimiter|key=value<delimiter>key=value
+func (p *Payload) parseLEEF(s string) {
+	parts := strings.SplitN(s, "|", 6)
+	if len(parts) < 5 {
+		return
+	}
+
+	p.Format = PayloadFormatLEEF
+	p.Version = strings.TrimPrefix(parts[0], "LEEF:")
+	p.Header["vendor"] = parts[1]
+	p.Header["product"] = parts[2]
+	p.Header["version"] = parts[3]
+	p.Header["event_id"] = parts[4]
+	if len(parts) < 6 {
+		return
+	}
+
+	attributes := parts[5]
+	delimiter := "\t"
+	if p.Version == "2.0" {
+		// The delimiter header is optional
+		if i := strings.IndexByte(attributes, '|'); i >= 0 && !strings.Contains(attributes[:i], "=") {
+			delimiter = parseLEEFDelimiter(attributes[:i])
+			attributes = attributes[i+1:]
+		}
+	}
+
+	for _, pair := range strings.Split(attributes, delimiter) {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			continue
+		}
+		p.Fields[strings.TrimSpace(pair[:i])] = pair[i+1:]
+	}
+}
+
+// parseLEEFDelimiter parses the LEEF 2.0 delimiter, which is either a
+// character or its hexadecimal code, such as x5E or 0x5E.
+func parseLEEFDelimiter(s string) string {
+	lower := strings.ToLower(s)
+	if len(s) > 1 && (strings.HasPrefix(lower, "0x") || strings.HasPrefix(lower, "x")) {
+		code, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimPrefix(lower, "0"), "x"), 16, 32)
+		if err == nil {
+			return string(rune(code))
+		}
+	}
+	if s == "" {
+		return "\t"
+	}
+
+	return s
+}
+
+// parseCEF parses a CEF content.
+//
+//	CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+func (p *Payload) parseCEF(s string) {
+	// Split the header on the unescaped pipes
+	parts := []string{}
+	var current strings.Builder
+	i := 0
+	for ; i < len(s) && len(parts) < 7; i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\'):
+			i++
+			current.WriteByte(s[i])
+		case s[i] == '|':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(s[i])
+		}
+	}
+	if len(parts) < 7 {
+		return
+	}
+
+	p.Format = PayloadFormatCEF
+	p.Version = strings.TrimPrefix(parts[0], "CEF:")
+	p.Header["vendor"] = parts[1]
+	p.Header["product"] = parts[2]
+	p.Header["version"] = parts[3]
+	p.Header["event_id"] = parts[4]
+	p.Header["name"] = parts[5]
+	p.Header["severity"] = parts[6]
+
+	// The values of the extensions can contain spaces, and end at the next key
+	extension := s[i:]
+	matches := cefKeyPattern.FindAllStringSubmatchIndex(extension, -1)
+	for j, match := range matches {
+		end := len(extension)
+		if j+1 < len(matches) {
+			end = matches[j+1][0]
+		}
+		key := extension[match[2]:match[3]]
+		p.Fields[key] = unescapeCEF(strings.TrimRight(extension[match[1]:end], " "))
+	}
+}
+
+func unescapeCEF(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
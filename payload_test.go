@@ -0,0 +1,92 @@
+package goqradar
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestParsePayloadLEEF(t *testing.T) {
+	p := ParsePayload("<13>Jan 18 11:07:53 host1 LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^usrName=joe^msg=a|b", time.Date(2021, 1, 18, 11, 8, 0, 0, time.UTC))
+	if p.Syslog == nil || p.Syslog.RFC != 3164 || p.Syslog.Hostname != "host1" || p.Syslog.Severity != 5 {
+		t.Fatalf("unexpected syslog header: %+v", p.Syslog)
+	}
+	if !p.Syslog.Timestamp.Equal(time.Date(2021, 1, 18, 11, 7, 53, 0, time.UTC)) {
+		t.Fatalf("unexpected timestamp: %s", p.Syslog.Timestamp)
+	}
+	if p.Format != PayloadFormatLEEF || p.Version != "2.0" || p.Header["vendor"] != "Lancope" || p.Header["event_id"] != "41" {
+		t.Fatalf("unexpected header: %s %s %v", p.Format, p.Version, p.Header)
+	}
+	if p.Fields["src"] != "10.0.1.8" || p.Fields["usrName"] != "joe" || p.Fields["msg"] != "a|b" {
+		t.Fatalf("unexpected fields: %v", p.Fields)
+	}
+
+	p = ParsePayload("LEEF:1.0|IBM|QRadar|7.4|login|src=10.0.0.1\tusrName=admin", time.Time{})
+	if p.Syslog != nil || p.Fields["src"] != "10.0.0.1" || p.Fields["usrName"] != "admin" {
+		t.Fatalf("unexpected payload: %+v", p)
+	}
+}
+
+func TestParsePayloadCEF(t *testing.T) {
+	p := ParsePayload(`<134>1 2020-09-13T12:26:40.000Z fw01 firewall 1234 ID47 [origin ip="10.0.0.1"] CEF:0|Security|threat\|manager|1.0|100|worm stopped|10|src=10.0.0.1 dst=2.1.2.2 msg=blocked a\=b attempt spt=1232`, time.Time{})
+	if p.Syslog == nil || p.Syslog.RFC != 5424 || p.Syslog.AppName != "firewall" || p.Syslog.StructuredData["origin"]["ip"] != "10.0.0.1" {
+		t.Fatalf("unexpected syslog header: %+v", p.Syslog)
+	}
+	if p.Format != PayloadFormatCEF || p.Header["product"] != "threat|manager" || p.Header["severity"] != "10" {
+		t.Fatalf("unexpected header: %v", p.Header)
+	}
+	if p.Fields["msg"] != "blocked a=b attempt" || p.Fields["spt"] != "1232" || p.Fields["dst"] != "2.1.2.2" {
+		t.Fatalf("unexpected fields: %v", p.Fields)
+	}
+}
+
+func TestPayloadsFromRows(t *testing.T) {
+	raw := "LEEF:1.0|IBM|QRadar|7.4|login|src=10.0.0.1"
+
+	// The payload column is base64 encoded
+	payloads, err := PayloadsFromRows([]map[string]interface{}{
+		{"payload": base64.StdEncoding.EncodeToString([]byte(raw))},
+		{"qid": 1},
+	}, "payload", true)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if len(payloads) != 1 || payloads[0].Raw != raw {
+		t.Fatalf("unexpected payloads: %+v", payloads)
+	}
+	if _, err := PayloadsFromRows([]map[string]interface{}{{"payload": raw}}, "payload", true); err == nil {
+		t.Fatal("should error with a payload which is not base64")
+	}
+
+	// UTF8(payload) is kept as is, even when it is valid base64
+	payloads, err = PayloadsFromRows([]map[string]interface{}{{"payload": raw}, {"payload": "dGVzdA=="}}, "payload", false)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if len(payloads) != 2 || payloads[0].Raw != raw || payloads[1].Raw != "dGVzdA==" {
+		t.Fatalf("unexpected payloads: %+v", payloads)
+	}
+	// The starttime is the reference time of the RFC 3164 timestamps
+	starttime := float64(epochMillis(time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)))
+	payloads, err = PayloadsFromRows([]map[string]interface{}{{"payload": "<13>Jun  1 00:00:00 host1 message", "starttime": starttime}}, "payload", false)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if payloads[0].Syslog.Timestamp.Year() != 2019 {
+		t.Fatalf("unexpected timestamp: %s", payloads[0].Syslog.Timestamp)
+	}
+}
+
+func TestParseSyslogHeaderYear(t *testing.T) {
+	// A message of December parsed in January is in the previous year
+	header, _ := parseSyslogHeader("<13>Dec 31 23:59:58 host1 message", time.Date(2021, 1, 1, 0, 0, 5, 0, time.UTC))
+	if !header.Timestamp.Equal(time.Date(2020, 12, 31, 23, 59, 58, 0, time.UTC)) {
+		t.Fatalf("unexpected timestamp: %s", header.Timestamp)
+	}
+
+	// And a message of January parsed in December is in the next year
+	header, _ = parseSyslogHeader("<13>Jan  1 00:00:02 host1 message", time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC))
+	if !header.Timestamp.Equal(time.Date(2021, 1, 1, 0, 0, 2, 0, time.UTC)) {
+		t.Fatalf("unexpected timestamp: %s", header.Timestamp)
+	}
+}
@@ -4,10 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"net/url"
 	"strconv"
+	"strings"
+)
+
+// Offense statuses.
+const (
+	OffenseStatusOpen   = "OPEN"
+	OffenseStatusHidden = "HIDDEN"
+	OffenseStatusClosed = "CLOSED"
 )
 
 //------------------------------------------------------------------------------
@@ -87,6 +93,24 @@ type Note struct {
 	Username   string `json:"username"`
 }
 
+// OffenseUpdate is the update of an offense. The nil fields are left
+// unchanged, so that follow_up and protected can be cleared with false.
+type OffenseUpdate struct {
+	Status          *string
+	AssignedTo      *string
+	ClosingReasonID *int
+	FollowUp        *bool
+	Protected       *bool
+}
+
+// NotesPaginatedResponse is the paginated response.
+type NotesPaginatedResponse struct {
+	Total int     `json:"total"`
+	Min   int     `json:"min"`
+	Max   int     `json:"max"`
+	Notes []*Note `json:"notes"`
+}
+
 // OffensePaginatedResponse is the paginated response.
 type OffensePaginatedResponse struct {
 	Total    int        `json:"total"`
@@ -164,6 +188,33 @@ type OffenseClosingReasonsPaginatedResponse struct {
 // Functions
 //------------------------------------------------------------------------------
 
+// Validate returns an error if the update is empty, if the status is unknown,
+// or if a closing reason is missing or given without closing the offense.
+func (u *OffenseUpdate) Validate() error {
+	if u == nil || (u.Status == nil && u.AssignedTo == nil && u.ClosingReasonID == nil && u.FollowUp == nil && u.Protected == nil) {
+		return fmt.Errorf("the update is empty")
+	}
+
+	if u.Status != nil {
+		switch *u.Status {
+		case OffenseStatusOpen, OffenseStatusHidden:
+			if u.ClosingReasonID != nil {
+				return fmt.Errorf("a closing reason requires the %s status", OffenseStatusClosed)
+			}
+		case OffenseStatusClosed:
+			if u.ClosingReasonID == nil || *u.ClosingReasonID <= 0 {
+				return fmt.Errorf("the %s status requires a closing reason", OffenseStatusClosed)
+			}
+		default:
+			return fmt.Errorf("invalid offense status: %s", *u.Status)
+		}
+	} else if u.ClosingReasonID != nil {
+		return fmt.Errorf("a closing reason requires the %s status", OffenseStatusClosed)
+	}
+
+	return nil
+}
+
 // ListOffenses returns the offenses with given fields, filters and sort.
 func (endpoint *Endpoint) ListOffenses(ctx context.Context, fields, filter, sort string, min, max int) (*OffensePaginatedResponse, error) {
 	// Options
@@ -225,6 +276,7 @@ func (endpoint *Endpoint) GetOffense(ctx context.Context, id int, fields string)
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
@@ -242,27 +294,31 @@ func (endpoint *Endpoint) GetOffense(ctx context.Context, id int, fields string)
 	return response, nil
 }
 
-// UpdateOffense with given ID.
-func (endpoint *Endpoint) UpdateOffense(ctx context.Context, id, closingReasonID int, assignedTo, fields, status string, followUp, protected bool) (*Offense, error) {
+// UpdateOffense updates the offense with given ID. Only the non-nil fields of the update are sent.
+func (endpoint *Endpoint) UpdateOffense(ctx context.Context, id int, update *OffenseUpdate, fields string) (*Offense, error) {
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+
 	// Options
 	options := []Option{}
-	if closingReasonID != 0 {
-		options = append(options, WithParam("closing_reason_id", strconv.Itoa(closingReasonID)))
+	if update.Status != nil {
+		options = append(options, WithParam("status", *update.Status))
 	}
-	if assignedTo != "" {
-		options = append(options, WithParam("assigned_to", assignedTo))
+	if update.AssignedTo != nil {
+		options = append(options, WithParam("assigned_to", *update.AssignedTo))
 	}
-	if fields != "" {
-		options = append(options, WithParam("fields", fields))
+	if update.ClosingReasonID != nil {
+		options = append(options, WithParam("closing_reason_id", strconv.Itoa(*update.ClosingReasonID)))
 	}
-	if status != "" {
-		options = append(options, WithParam("status", status))
+	if update.FollowUp != nil {
+		options = append(options, WithParam("follow_up", strconv.FormatBool(*update.FollowUp)))
 	}
-	if followUp != false {
-		options = append(options, WithParam("follow_up", strconv.FormatBool(followUp)))
+	if update.Protected != nil {
+		options = append(options, WithParam("protected", strconv.FormatBool(*update.Protected)))
 	}
-	if protected != false {
-		options = append(options, WithParam("protected", strconv.FormatBool(protected)))
+	if fields != "" {
+		options = append(options, WithParam("fields", fields))
 	}
 
 	// Do the request
@@ -270,6 +326,7 @@ func (endpoint *Endpoint) UpdateOffense(ctx context.Context, id, closingReasonID
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
@@ -287,35 +344,75 @@ func (endpoint *Endpoint) UpdateOffense(ctx context.Context, id, closingReasonID
 	return response, nil
 }
 
-// ListOffenseNotes returns the notes of the given offense.
-func (endpoint *Endpoint) ListOffenseNotes(ctx context.Context, id string) ([]*Note, int, error) {
+// CloseOffense closes the offense with given ID and closing reason.
+func (endpoint *Endpoint) CloseOffense(ctx context.Context, id, closingReasonID int) (*Offense, error) {
+	status := OffenseStatusClosed
+
+	return endpoint.UpdateOffense(ctx, id, &OffenseUpdate{Status: &status, ClosingReasonID: &closingReasonID}, "")
+}
+
+// AssignOffense assigns the offense with given ID to the user.
+func (endpoint *Endpoint) AssignOffense(ctx context.Context, id int, assignedTo string) (*Offense, error) {
+	return endpoint.UpdateOffense(ctx, id, &OffenseUpdate{AssignedTo: &assignedTo}, "")
+}
+
+// SetFollowUp sets or clears the follow-up flag of the offense with given ID.
+func (endpoint *Endpoint) SetFollowUp(ctx context.Context, id int, followUp bool) (*Offense, error) {
+	return endpoint.UpdateOffense(ctx, id, &OffenseUpdate{FollowUp: &followUp}, "")
+}
+
+// ProtectOffense protects or unprotects the offense with given ID.
+func (endpoint *Endpoint) ProtectOffense(ctx context.Context, id int, protected bool) (*Offense, error) {
+	return endpoint.UpdateOffense(ctx, id, &OffenseUpdate{Protected: &protected}, "")
+}
+
+// ListOffenseNotes returns the notes of the given offense with given fields and filter.
+func (endpoint *Endpoint) ListOffenseNotes(ctx context.Context, offenseID int, fields, filter string, min, max int) (*NotesPaginatedResponse, error) {
 	// Options
 	options := []Option{}
+	if fields != "" {
+		options = append(options, WithParam("fields", fields))
+	}
+	if filter != "" {
+		options = append(options, WithParam("filter", filter))
+	}
+	options = append(options, WithHeader("Range", fmt.Sprintf("items=%d-%d", min, max)))
 
 	// Do the request
-	resp, err := endpoint.client.do(ctx, http.MethodGet, "/siem/offenses/"+id+"/notes", options...)
+	resp, err := endpoint.client.do(ctx, http.MethodGet, "/siem/offenses/"+strconv.Itoa(offenseID)+"/notes", options...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("error while calling the endpoint: %s", err)
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, 0, fmt.Errorf("error with the status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	// Process the Content-Range
+	min, max, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing the content-range [%s]: %s", resp.Header.Get("Content-Range"), err)
 	}
 
 	// Prepare the response
-	var response []*Note
+	response := &NotesPaginatedResponse{
+		Total: total,
+		Min:   min,
+		Max:   max,
+	}
 
 	// Decode the response
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err = json.NewDecoder(resp.Body).Decode(&response.Notes)
 	if err != nil {
-		return nil, 0, fmt.Errorf("error while decoding the response: %s", err)
+		return nil, fmt.Errorf("error while decoding the response: %s", err)
 	}
 
-	return response, len(response), nil
+	return response, nil
 }
 
-// CreateOffenseNote ...
-func (endpoint *Endpoint) CreateOffenseNote(ctx context.Context, id int, noteText, fields string) (*Note, error) {
+// GetOffenseNote returns the note by given ID of the given offense.
+func (endpoint *Endpoint) GetOffenseNote(ctx context.Context, offenseID, noteID int, fields string) (*Note, error) {
 	// Options
 	options := []Option{}
 	if fields != "" {
@@ -323,10 +420,42 @@ func (endpoint *Endpoint) CreateOffenseNote(ctx context.Context, id int, noteTex
 	}
 
 	// Do the request
-	resp, err := endpoint.client.do(ctx, http.MethodPost, "/siem/offenses/"+strconv.Itoa(id)+"/notes?note_text="+noteText, options...)
+	resp, err := endpoint.client.do(ctx, http.MethodGet, "/siem/offenses/"+strconv.Itoa(offenseID)+"/notes/"+strconv.Itoa(noteID), options...)
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	// Prepare the response
+	var response *Note
+
+	// Decode the response
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding the response: %s", err)
+	}
+
+	return response, nil
+}
+
+// CreateOffenseNote creates a note on the given offense.
+func (endpoint *Endpoint) CreateOffenseNote(ctx context.Context, offenseID int, noteText, fields string) (*Note, error) {
+	// Options
+	options := []Option{WithParam("note_text", noteText)}
+	if fields != "" {
+		options = append(options, WithParam("fields", fields))
+	}
+
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodPost, "/siem/offenses/"+strconv.Itoa(offenseID)+"/notes", options...)
+	if err != nil {
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 201 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
@@ -344,6 +473,22 @@ func (endpoint *Endpoint) CreateOffenseNote(ctx context.Context, id int, noteTex
 	return response, nil
 }
 
+// DeleteOffenseNote deletes the note by given ID of the given offense.
+func (endpoint *Endpoint) DeleteOffenseNote(ctx context.Context, offenseID, noteID int) error {
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodDelete, "/siem/offenses/"+strconv.Itoa(offenseID)+"/notes/"+strconv.Itoa(noteID))
+	if err != nil {
+		return fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 204 {
+		return fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 //------------------------------------------------------------------------------
 
 // ListOffenseTypes returns the offenses type with given fields, filters and sort.
@@ -406,6 +551,7 @@ func (endpoint *Endpoint) GetOffenseType(ctx context.Context, id, fields string)
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
@@ -470,6 +616,7 @@ func (endpoint *Endpoint) GetLocalDestinationAddress(ctx context.Context, id int
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
@@ -544,6 +691,7 @@ func (endpoint *Endpoint) GetSourceAddress(ctx context.Context, id int, fields s
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
@@ -562,7 +710,7 @@ func (endpoint *Endpoint) GetSourceAddress(ctx context.Context, id int, fields s
 }
 
 // ListOffenseClosingReasons returns a list of all offense closing reasons with given fields, filters and sort.
-func (endpoint *Endpoint) ListOffenseClosingReasons(ctx context.Context, fields, filter string, includeDeleted, includedReserved bool, min, max int) (*OffenseClosingReasonsPaginatedResponse, error) {
+func (endpoint *Endpoint) ListOffenseClosingReasons(ctx context.Context, fields, filter string, includeDeleted, includeReserved bool, min, max int) (*OffenseClosingReasonsPaginatedResponse, error) {
 	// Options
 	options := []Option{}
 	if fields != "" {
@@ -574,8 +722,8 @@ func (endpoint *Endpoint) ListOffenseClosingReasons(ctx context.Context, fields,
 	if includeDeleted != false {
 		options = append(options, WithParam("include_deleted", strconv.FormatBool(includeDeleted)))
 	}
-	if includedReserved != false {
-		options = append(options, WithParam("included_reserved", strconv.FormatBool(includedReserved)))
+	if includeReserved != false {
+		options = append(options, WithParam("include_reserved", strconv.FormatBool(includeReserved)))
 	}
 	options = append(options, WithHeader("Range", fmt.Sprintf("items=%d-%d", min, max)))
 
@@ -584,6 +732,7 @@ func (endpoint *Endpoint) ListOffenseClosingReasons(ctx context.Context, fields,
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
@@ -613,55 +762,118 @@ func (endpoint *Endpoint) ListOffenseClosingReasons(ctx context.Context, fields,
 
 // CreateOffenseClosingReason create an offense closing reason.
 func (endpoint *Endpoint) CreateOffenseClosingReason(ctx context.Context, reason, fields string) (*OffenseClosingReason, error) {
-	// Prepare the URL
-	var reqURL *url.URL
-	reqURL, err := url.Parse(endpoint.client.BaseURL)
+	if strings.TrimSpace(reason) == "" {
+		return nil, fmt.Errorf("the text of the closing reason is required")
+	}
+
+	// Options
+	options := []Option{WithParam("reason", reason)}
+	if fields != "" {
+		options = append(options, WithParam("fields", fields))
+	}
+
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodPost, "/siem/offense_closing_reasons", options...)
 	if err != nil {
-		return nil, fmt.Errorf("Error while parsing the URL : %s", err)
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 201 {
+		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
 	}
-	reqURL.Path += "/siem/offense_closing_reasons"
 
-	// Create the request
-	req, err := http.NewRequest("POST", reqURL.String(), nil)
+	// Prepare the response
+	var response *OffenseClosingReason
+
+	// Decode the response
+	err = json.NewDecoder(resp.Body).Decode(&response)
 	if err != nil {
-		return nil, fmt.Errorf("Error while creating the request : %s", err)
+		return nil, fmt.Errorf("error while decoding the response: %s", err)
 	}
 
-	// Add optional parameters
-	q := req.URL.Query()
-	q.Add("reason", reason)
-	q.Add("filters", fields)
-	req.URL.RawQuery = q.Encode()
+	return response, nil
+}
 
-	// Set HTTP headers
-	req.Header.Set("SEC", endpoint.client.Token)
-	req.Header.Set("Version", endpoint.client.Version)
-	req.Header.Set("Content-Type", "application/json")
+// UpdateOffenseClosingReason updates the text of the offense closing reason
+// with given ID. The reserved and the deleted reasons cannot be updated.
+func (endpoint *Endpoint) UpdateOffenseClosingReason(ctx context.Context, id int, reason, fields string) (*OffenseClosingReason, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, fmt.Errorf("the text of the closing reason is required")
+	}
 
-	// Do the request
-	resp, err := endpoint.client.client.Do(req)
+	// Check the reason
+	current, err := endpoint.GetOffenseClosingReason(ctx, id, "id,is_deleted,is_reserved")
 	if err != nil {
-		return nil, fmt.Errorf("Error while doing the request : %s", err)
+		return nil, fmt.Errorf("error while retrieving the closing reason: %s", err)
+	}
+	if current.IsReserved {
+		return nil, fmt.Errorf("the closing reason %d is reserved", id)
+	}
+	if current.IsDeleted {
+		return nil, fmt.Errorf("the closing reason %d is deleted", id)
+	}
+
+	// Options
+	options := []Option{WithParam("reason", reason)}
+	if fields != "" {
+		options = append(options, WithParam("fields", fields))
 	}
 
-	// Read the response
-	body, err := ioutil.ReadAll(resp.Body)
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodPost, "/siem/offense_closing_reasons/"+strconv.Itoa(id), options...)
 	if err != nil {
-		return nil, fmt.Errorf("error while reading the request : %s", err)
+		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
 	}
 
 	// Prepare the response
 	var response *OffenseClosingReason
 
-	// Unmarshal the response
-	err = json.Unmarshal([]byte(body), &response)
+	// Decode the response
+	err = json.NewDecoder(resp.Body).Decode(&response)
 	if err != nil {
-		return nil, fmt.Errorf("Error while unmarshalling the response : %s. HTTP response is : %s", err, string(body))
+		return nil, fmt.Errorf("error while decoding the response: %s", err)
 	}
 
 	return response, nil
 }
 
+// DeleteOffenseClosingReason deletes the offense closing reason with given ID.
+// QRadar keeps the deleted reasons, flagged with is_deleted, for the closed
+// offenses which use them. Deleting a deleted reason does nothing, deleting a
+// reserved reason is an error.
+func (endpoint *Endpoint) DeleteOffenseClosingReason(ctx context.Context, id int) error {
+	// Check the reason
+	current, err := endpoint.GetOffenseClosingReason(ctx, id, "id,is_deleted,is_reserved")
+	if err != nil {
+		return fmt.Errorf("error while retrieving the closing reason: %s", err)
+	}
+	if current.IsReserved {
+		return fmt.Errorf("the closing reason %d is reserved", id)
+	}
+	if current.IsDeleted {
+		return nil
+	}
+
+	// Do the request
+	resp, err := endpoint.client.do(ctx, http.MethodDelete, "/siem/offense_closing_reasons/"+strconv.Itoa(id))
+	if err != nil {
+		return fmt.Errorf("error while calling the endpoint: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 204 {
+		return fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 // GetOffenseClosingReason retrieve an offense closing reason.
 func (endpoint *Endpoint) GetOffenseClosingReason(ctx context.Context, id int, fields string) (*OffenseClosingReason, error) {
 	// Options
@@ -675,6 +887,7 @@ func (endpoint *Endpoint) GetOffenseClosingReason(ctx context.Context, id int, f
 	if err != nil {
 		return nil, fmt.Errorf("error while calling the endpoint: %s", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("error with the status code: %d", resp.StatusCode)
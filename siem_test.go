@@ -0,0 +1,171 @@
+package goqradar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestOffenseUpdateValidate(t *testing.T) {
+	closed, open := OffenseStatusClosed, OffenseStatusOpen
+	reason := 1
+
+	if err := (&OffenseUpdate{}).Validate(); err == nil {
+		t.Fatal("should error with an empty update")
+	}
+	if err := (&OffenseUpdate{Status: &closed}).Validate(); err == nil {
+		t.Fatal("should error when closing without a reason")
+	}
+	if err := (&OffenseUpdate{Status: &open, ClosingReasonID: &reason}).Validate(); err == nil {
+		t.Fatal("should error with a closing reason without closing")
+	}
+	if err := (&OffenseUpdate{Status: &closed, ClosingReasonID: &reason}).Validate(); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+}
+
+func TestSetFollowUp(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{"id":42,"follow_up":false}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	offense, err := client.SIEM.SetFollowUp(context.Background(), 42, false)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if offense.ID != 42 {
+		t.Fatalf("unexpected offense: %+v", offense)
+	}
+
+	// The false values must be sent
+	if query.Get("follow_up") != "false" || len(query) != 1 {
+		t.Fatalf("unexpected parameters: %v", query)
+	}
+}
+
+// notesServer serves the notes of the offense 42 from memory, with the Range header.
+func notesServer(t *testing.T, notes []*Note) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/api/siem/offenses/42/notes")
+		if path == r.URL.Path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if path == "" {
+			var min, max int
+			if _, err := fmt.Sscanf(r.Header.Get("Range"), "items=%d-%d", &min, &max); err != nil {
+				t.Errorf("invalid Range header: %s", r.Header.Get("Range"))
+			}
+			if max >= len(notes) {
+				max = len(notes) - 1
+			}
+			if min > max {
+				w.Header().Set("Content-Range", fmt.Sprintf("items */%d", len(notes)))
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("items %d-%d/%d", min, max, len(notes)))
+			json.NewEncoder(w).Encode(notes[min : max+1])
+			return
+		}
+
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, "/"))
+		for i, note := range notes {
+			if note.ID != id {
+				continue
+			}
+			switch r.Method {
+			case http.MethodGet:
+				json.NewEncoder(w).Encode(note)
+			case http.MethodDelete:
+				notes = append(notes[:i], notes[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+}
+
+func TestListOffenseNotes(t *testing.T) {
+	notes := []*Note{}
+	for id := 1; id <= 120; id++ {
+		notes = append(notes, &Note{ID: id, NoteText: "note " + strconv.Itoa(id)})
+	}
+	server := notesServer(t, notes)
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	page, err := client.SIEM.ListOffenseNotes(context.Background(), 42, "", "", 10, 19)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if page.Min != 10 || page.Max != 19 || page.Total != 120 || len(page.Notes) != 10 || page.Notes[0].ID != 11 {
+		t.Fatalf("unexpected page: %d-%d/%d %d notes", page.Min, page.Max, page.Total, len(page.Notes))
+	}
+
+	// Every page is retrieved
+	all, err := listAllOffenseNotes(context.Background(), client.SIEM, 42)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if len(all) != 120 || all[0].ID != 1 || all[119].ID != 120 {
+		t.Fatalf("unexpected notes: %d", len(all))
+	}
+
+	// The status code is checked
+	if _, err := client.SIEM.ListOffenseNotes(context.Background(), 43, "", "", 0, 49); err == nil {
+		t.Fatal("should error with an unknown offense")
+	}
+}
+
+func TestListOffenseNotesEmpty(t *testing.T) {
+	server := notesServer(t, []*Note{})
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	all, err := listAllOffenseNotes(context.Background(), client.SIEM, 42)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if len(all) != 0 {
+		t.Fatalf("unexpected notes: %d", len(all))
+	}
+}
+
+func TestGetAndDeleteOffenseNote(t *testing.T) {
+	server := notesServer(t, []*Note{{ID: 1, NoteText: "first"}, {ID: 2, NoteText: "second", Username: "admin"}})
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	ctx := context.Background()
+
+	note, err := client.SIEM.GetOffenseNote(ctx, 42, 2, "")
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if note.ID != 2 || note.NoteText != "second" || note.Username != "admin" {
+		t.Fatalf("unexpected note: %+v", note)
+	}
+
+	if err := client.SIEM.DeleteOffenseNote(ctx, 42, 2); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if _, err := client.SIEM.GetOffenseNote(ctx, 42, 2, ""); err == nil {
+		t.Fatal("should error with a deleted note")
+	}
+	if err := client.SIEM.DeleteOffenseNote(ctx, 42, 2); err == nil {
+		t.Fatal("should error with a deleted note")
+	}
+}
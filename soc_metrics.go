@@ -0,0 +1,403 @@
+package goqradar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMetricsPageSize    = 100
+	defaultMetricsConcurrency = 5
+	unassigned                = "(unassigned)"
+)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// SOCMetricsOptions are the options of the computation of the SOC metrics.
+type SOCMetricsOptions struct {
+	// Start and Stop select the offenses by start time.
+	Start time.Time
+	Stop  time.Time
+
+	// Filter is an additional filter of the offenses, such as "domain_id = 1".
+	Filter string
+
+	// PageSize is the number of offenses fetched per request. Default is 100.
+	PageSize int
+
+	// Concurrency is the maximum number of concurrent requests of notes, offense
+	// types and closing reasons. Default is 5.
+	Concurrency int
+
+	// SkipNotes does not retrieve the notes, the times to assign are not computed.
+	SkipNotes bool
+}
+
+// SOCMetrics are the performance metrics of a SOC over the offenses started in
+// a time range. The durations are in seconds.
+//
+// QRadar does not record the time of the assignment, the time to assign is the
+// time between the start of an assigned offense and its first note.
+type SOCMetrics struct {
+	Start time.Time `json:"start"`
+	Stop  time.Time `json:"stop"`
+
+	SOCGroupMetrics
+
+	// BacklogByMagnitude is the number of offenses not closed, by magnitude.
+	BacklogByMagnitude map[int]int `json:"backlog_by_magnitude"`
+
+	// ClosuresByReason is the number of closed offenses, by closing reason.
+	ClosuresByReason map[string]int `json:"closures_by_reason"`
+
+	// ClosuresByAnalyst is the number of closed offenses, by closing user.
+	ClosuresByAnalyst map[string]int `json:"closures_by_analyst"`
+
+	ByAssignee    map[string]*SOCGroupMetrics `json:"by_assignee"`
+	ByOffenseType map[string]*SOCGroupMetrics `json:"by_offense_type"`
+}
+
+// SOCGroupMetrics are the metrics of a group of offenses.
+type SOCGroupMetrics struct {
+	Offenses                int     `json:"offenses"`
+	Open                    int     `json:"open"`
+	Closed                  int     `json:"closed"`
+	MeanTimeToAssignSeconds float64 `json:"mean_time_to_assign_seconds"`
+
+	// MeanTimeToCloseSeconds is the mean over the closed offenses with a close
+	// time after their start time.
+	MeanTimeToCloseSeconds float64 `json:"mean_time_to_close_seconds"`
+
+	assigned int
+	timed    int
+	toAssign float64
+	toClose  float64
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// ComputeSOCMetrics walks the offenses started in the time range, with their
+// notes, offense types and closing reasons, and computes the SOC metrics.
+func (endpoint *Endpoint) ComputeSOCMetrics(ctx context.Context, opts *SOCMetricsOptions) (*SOCMetrics, error) {
+	if opts == nil || opts.Start.IsZero() || !opts.Stop.After(opts.Start) {
+		return nil, fmt.Errorf("a valid time range is required")
+	}
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultMetricsPageSize
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultMetricsConcurrency
+	}
+
+	// Retrieve the offenses
+	filter := fmt.Sprintf("start_time >= %d and start_time < %d", epochMillis(opts.Start), epochMillis(opts.Stop))
+	if opts.Filter != "" {
+		filter = "(" + opts.Filter + ") and " + filter
+	}
+	fields := "id,status,magnitude,offense_type,assigned_to,start_time,close_time,closing_user,closing_reason_id"
+
+	offenses := []*Offense{}
+	for min := 0; ; min += pageSize {
+		page, err := endpoint.ListOffenses(ctx, fields, filter, "+id", min, min+pageSize-1)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing the offenses: %s", err)
+		}
+		offenses = append(offenses, page.Offenses...)
+		if len(page.Offenses) < pageSize || min+pageSize >= page.Total {
+			break
+		}
+	}
+
+	// Resolve the notes, the offense types and the closing reasons
+	enricher := NewOffenseEnricher(endpoint, endpoint.client.Analytics, nil)
+	notes := map[int][]*Note{}
+	offenseTypes := map[int]string{}
+	closingReasons := map[int]string{}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	semaphore := make(chan struct{}, concurrency)
+	run := func(fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+
+			if err := fn(); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}()
+	}
+
+	for _, offense := range offenses {
+		offense := offense
+		if !opts.SkipNotes && offense.AssignedTo != "" {
+			run(func() error {
+				offenseNotes, err := listAllOffenseNotes(ctx, endpoint, offense.ID)
+				if err != nil {
+					return fmt.Errorf("error while listing the notes of the offense %d: %s", offense.ID, err)
+				}
+				mu.Lock()
+				notes[offense.ID] = offenseNotes
+				mu.Unlock()
+				return nil
+			})
+		}
+
+		mu.Lock()
+		// The IDs are placeholders until the names are resolved
+		_, typeQueued := offenseTypes[offense.OffenseType]
+		if !typeQueued {
+			offenseTypes[offense.OffenseType] = strconv.Itoa(offense.OffenseType)
+		}
+		_, reasonQueued := closingReasons[offense.ClosingReasonID]
+		if !reasonQueued && offense.ClosingReasonID != 0 {
+			closingReasons[offense.ClosingReasonID] = strconv.Itoa(offense.ClosingReasonID)
+		}
+		mu.Unlock()
+
+		if !typeQueued {
+			run(func() error {
+				offenseType, err := enricher.offenseType(ctx, offense.OffenseType)
+				if err != nil {
+					return fmt.Errorf("error while retrieving the offense type %d: %s", offense.OffenseType, err)
+				}
+				mu.Lock()
+				offenseTypes[offense.OffenseType] = offenseType.Name
+				mu.Unlock()
+				return nil
+			})
+		}
+		if !reasonQueued && offense.ClosingReasonID != 0 {
+			run(func() error {
+				reason, err := enricher.closingReason(ctx, offense.ClosingReasonID)
+				if err != nil {
+					return fmt.Errorf("error while retrieving the closing reason %d: %s", offense.ClosingReasonID, err)
+				}
+				mu.Lock()
+				closingReasons[offense.ClosingReasonID] = reason.Text
+				mu.Unlock()
+				return nil
+			})
+		}
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	metrics := computeSOCMetrics(offenses, notes, offenseTypes, closingReasons)
+	metrics.Start, metrics.Stop = opts.Start, opts.Stop
+
+	return metrics, nil
+}
+
+// WriteJSON writes the metrics as JSON.
+func (m *SOCMetrics) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(m); err != nil {
+		return fmt.Errorf("error while writing the metrics: %s", err)
+	}
+
+	return nil
+}
+
+// WriteCSV writes the metrics as CSV, one row per dimension and key. The
+// backlog and the closures only fill the offenses column.
+func (m *SOCMetrics) WriteCSV(w io.Writer) error {
+	records := [][]string{{"dimension", "key", "offenses", "open", "closed", "mean_time_to_assign_seconds", "mean_time_to_close_seconds"}}
+
+	group := func(dimension, key string, g *SOCGroupMetrics) {
+		records = append(records, []string{
+			dimension,
+			key,
+			strconv.Itoa(g.Offenses),
+			strconv.Itoa(g.Open),
+			strconv.Itoa(g.Closed),
+			strconv.FormatFloat(g.MeanTimeToAssignSeconds, 'f', 0, 64),
+			strconv.FormatFloat(g.MeanTimeToCloseSeconds, 'f', 0, 64),
+		})
+	}
+	count := func(dimension string, counts map[string]int) {
+		for _, key := range sortedKeys(counts) {
+			records = append(records, []string{dimension, key, strconv.Itoa(counts[key]), "", "", "", ""})
+		}
+	}
+
+	group("total", "", &m.SOCGroupMetrics)
+	for _, key := range sortedGroupKeys(m.ByAssignee) {
+		group("assignee", key, m.ByAssignee[key])
+	}
+	for _, key := range sortedGroupKeys(m.ByOffenseType) {
+		group("offense_type", key, m.ByOffenseType[key])
+	}
+
+	magnitudes := []int{}
+	for magnitude := range m.BacklogByMagnitude {
+		magnitudes = append(magnitudes, magnitude)
+	}
+	sort.Ints(magnitudes)
+	for _, magnitude := range magnitudes {
+		records = append(records, []string{"backlog_magnitude", strconv.Itoa(magnitude), strconv.Itoa(m.BacklogByMagnitude[magnitude]), "", "", "", ""})
+	}
+
+	count("closing_reason", m.ClosuresByReason)
+	count("closing_analyst", m.ClosuresByAnalyst)
+
+	return writeCSVRecords(w, records)
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// computeSOCMetrics computes the metrics of the offenses, with the notes by
+// offense, and the names of the offense types and of the closing reasons.
+func computeSOCMetrics(offenses []*Offense, notes map[int][]*Note, offenseTypes, closingReasons map[int]string) *SOCMetrics {
+	m := &SOCMetrics{
+		BacklogByMagnitude: map[int]int{},
+		ClosuresByReason:   map[string]int{},
+		ClosuresByAnalyst:  map[string]int{},
+		ByAssignee:         map[string]*SOCGroupMetrics{},
+		ByOffenseType:      map[string]*SOCGroupMetrics{},
+	}
+
+	for _, offense := range offenses {
+		assignee := offense.AssignedTo
+		if assignee == "" {
+			assignee = unassigned
+		}
+		offenseType := offenseTypes[offense.OffenseType]
+		if offenseType == "" {
+			offenseType = strconv.Itoa(offense.OffenseType)
+		}
+
+		groups := []*SOCGroupMetrics{&m.SOCGroupMetrics, metricsGroup(m.ByAssignee, assignee), metricsGroup(m.ByOffenseType, offenseType)}
+		for _, g := range groups {
+			g.Offenses++
+		}
+
+		// Time to assign, up to the first note
+		if offense.AssignedTo != "" {
+			first := 0
+			for _, note := range notes[offense.ID] {
+				if first == 0 || note.CreateTime < first {
+					first = note.CreateTime
+				}
+			}
+			if first > 0 && first >= offense.StartTime {
+				for _, g := range groups {
+					g.assigned++
+					g.toAssign += float64(first-offense.StartTime) / 1000
+				}
+			}
+		}
+
+		// Time to close
+		if offense.Status != OffenseStatusClosed {
+			m.BacklogByMagnitude[offense.Magnitude]++
+			for _, g := range groups {
+				g.Open++
+			}
+			continue
+		}
+
+		for _, g := range groups {
+			g.Closed++
+			if offense.CloseTime >= offense.StartTime {
+				g.timed++
+				g.toClose += float64(offense.CloseTime-offense.StartTime) / 1000
+			}
+		}
+
+		reason := closingReasons[offense.ClosingReasonID]
+		if reason == "" {
+			reason = strconv.Itoa(offense.ClosingReasonID)
+		}
+		m.ClosuresByReason[reason]++
+		m.ClosuresByAnalyst[offense.ClosingUser]++
+	}
+
+	// Compute the means
+	all := []*SOCGroupMetrics{&m.SOCGroupMetrics}
+	for _, g := range m.ByAssignee {
+		all = append(all, g)
+	}
+	for _, g := range m.ByOffenseType {
+		all = append(all, g)
+	}
+	for _, g := range all {
+		if g.assigned > 0 {
+			g.MeanTimeToAssignSeconds = g.toAssign / float64(g.assigned)
+		}
+		if g.timed > 0 {
+			g.MeanTimeToCloseSeconds = g.toClose / float64(g.timed)
+		}
+	}
+
+	return m
+}
+
+func metricsGroup(groups map[string]*SOCGroupMetrics, key string) *SOCGroupMetrics {
+	g, ok := groups[key]
+	if !ok {
+		g = &SOCGroupMetrics{}
+		groups[key] = g
+	}
+
+	return g
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func sortedGroupKeys(m map[string]*SOCGroupMetrics) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
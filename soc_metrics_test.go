@@ -0,0 +1,119 @@
+package goqradar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestComputeSOCMetrics(t *testing.T) {
+	offenses := []*Offense{
+		{ID: 1, Status: OffenseStatusClosed, AssignedTo: "alice", OffenseType: 0, Magnitude: 5, StartTime: 0, CloseTime: 3600000, ClosingUser: "alice", ClosingReasonID: 1},
+		{ID: 2, Status: OffenseStatusClosed, AssignedTo: "alice", OffenseType: 0, Magnitude: 3, StartTime: 1000000, CloseTime: 2800000, ClosingUser: "bob", ClosingReasonID: 2},
+		{ID: 3, Status: OffenseStatusOpen, AssignedTo: "", OffenseType: 1, Magnitude: 3, StartTime: 2000000},
+	}
+	notes := map[int][]*Note{
+		1: {{CreateTime: 1200000}, {CreateTime: 600000}},
+		2: {{CreateTime: 1600000}},
+	}
+	offenseTypes := map[int]string{0: "Source IP", 1: "Username"}
+	closingReasons := map[int]string{1: "False-Positive", 2: "Non-Issue"}
+
+	m := computeSOCMetrics(offenses, notes, offenseTypes, closingReasons)
+
+	if m.Offenses != 3 || m.Open != 1 || m.Closed != 2 {
+		t.Fatalf("unexpected counts: %+v", m.SOCGroupMetrics)
+	}
+	if m.MeanTimeToAssignSeconds != 600 || m.MeanTimeToCloseSeconds != 2700 {
+		t.Fatalf("unexpected means: %+v", m.SOCGroupMetrics)
+	}
+	if m.BacklogByMagnitude[3] != 1 || m.ClosuresByReason["Non-Issue"] != 1 || m.ClosuresByAnalyst["alice"] != 1 {
+		t.Fatalf("unexpected breakdowns: %+v", m)
+	}
+	if m.ByAssignee["alice"].Closed != 2 || m.ByAssignee[unassigned].Open != 1 || m.ByOffenseType["Username"].Offenses != 1 {
+		t.Fatalf("unexpected groups: %+v %+v", m.ByAssignee, m.ByOffenseType)
+	}
+
+	// The offenses without a valid close time are not part of the mean time to close
+	closed := append(offenses, &Offense{ID: 4, Status: OffenseStatusClosed, OffenseType: 1, StartTime: 5000000, ClosingReasonID: 1})
+	if other := computeSOCMetrics(closed, notes, offenseTypes, closingReasons); other.Closed != 3 || other.MeanTimeToCloseSeconds != 2700 {
+		t.Fatalf("unexpected mean time to close: %+v", other.SOCGroupMetrics)
+	}
+
+	var b bytes.Buffer
+	if err := m.WriteCSV(&b); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if lines[1] != "total,,3,1,2,600,2700" || lines[2] != "assignee,(unassigned),1,1,0,0,0" {
+		t.Fatalf("unexpected CSV:\n%s", b.String())
+	}
+}
+
+func TestComputeSOCMetricsResolvesNames(t *testing.T) {
+	// The offenses share the offense type and the closing reason
+	offenses := []*Offense{}
+	for id := 1; id <= 50; id++ {
+		offenses = append(offenses, &Offense{ID: id, Status: OffenseStatusClosed, AssignedTo: "alice", OffenseType: 0, StartTime: 1000, CloseTime: 61000, ClosingUser: "alice", ClosingReasonID: 1})
+	}
+	offenses = append(offenses, &Offense{ID: 51, Status: OffenseStatusOpen, OffenseType: 1, StartTime: 1000})
+
+	var (
+		mu    sync.Mutex
+		calls = map[string]int{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/api/siem/offenses":
+			w.Header().Set("Content-Range", fmt.Sprintf("items 0-%d/%d", len(offenses)-1, len(offenses)))
+			json.NewEncoder(w).Encode(offenses)
+		case strings.HasSuffix(r.URL.Path, "/notes"):
+			w.Header().Set("Content-Range", "items 0-0/1")
+			w.Write([]byte(`[{"id":1,"create_time":31000}]`))
+		case r.URL.Path == "/api/siem/offense_types/0":
+			w.Write([]byte(`{"id":0,"name":"Source IP"}`))
+		case r.URL.Path == "/api/siem/offense_types/1":
+			w.Write([]byte(`{"id":1,"name":"Username"}`))
+		case r.URL.Path == "/api/siem/offense_closing_reasons/1":
+			w.Write([]byte(`{"id":1,"text":"False-Positive"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, server.URL, "token")
+	m, err := client.SIEM.ComputeSOCMetrics(context.Background(), &SOCMetricsOptions{
+		Start: time.Unix(0, 0),
+		Stop:  time.Unix(3600, 0),
+	})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	if m.Offenses != 51 || m.Closed != 50 || m.MeanTimeToAssignSeconds != 30 || m.MeanTimeToCloseSeconds != 60 {
+		t.Fatalf("unexpected metrics: %+v", m.SOCGroupMetrics)
+	}
+	if len(m.ClosuresByReason) != 1 || m.ClosuresByReason["False-Positive"] != 50 {
+		t.Fatalf("unexpected closures: %v", m.ClosuresByReason)
+	}
+	if len(m.ByOffenseType) != 2 || m.ByOffenseType["Source IP"].Offenses != 50 || m.ByOffenseType["Username"].Offenses != 1 {
+		t.Fatalf("unexpected offense types: %v", m.ByOffenseType)
+	}
+
+	// The names are resolved once
+	if calls["/api/siem/offense_types/0"] != 1 || calls["/api/siem/offense_closing_reasons/1"] != 1 {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+}
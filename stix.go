@@ -0,0 +1,349 @@
+package goqradar
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	stixTimeFormat = "2006-01-02T15:04:05.000Z"
+
+	// stixIdentityTime is the creation time of the identity, which is shared
+	// by the bundles of every offense, so that its versions do not conflict.
+	stixIdentityTime = "1970-01-01T00:00:00.000Z"
+)
+
+var (
+	// stixSCONamespace is the namespace of the deterministic IDs of the STIX
+	// cyber-observable objects, defined by the STIX 2.1 specification.
+	stixSCONamespace = mustParseUUID("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+	// stixSDONamespace is the namespace of the IDs of the other objects, the
+	// URL namespace of RFC 4122.
+	stixSDONamespace = mustParseUUID("6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// STIXOptions are the options of a STIX export.
+type STIXOptions struct {
+	// Namespace is part of the IDs, so that the offenses of several consoles
+	// do not collide. It is usually the hostname of the console.
+	Namespace string
+
+	// IdentityName is the name of the organization which creates the objects. It is optional.
+	IdentityName string
+
+	// Indent indents the JSON.
+	Indent bool
+}
+
+// STIXBundle is a STIX 2.1 bundle.
+type STIXBundle struct {
+	Type    string        `json:"type"`
+	ID      string        `json:"id"`
+	Objects []*STIXObject `json:"objects"`
+}
+
+// STIXObject is a STIX 2.1 object. Only the properties of its type are set.
+type STIXObject struct {
+	Type               string                   `json:"type"`
+	SpecVersion        string                   `json:"spec_version"`
+	ID                 string                   `json:"id"`
+	CreatedByRef       string                   `json:"created_by_ref,omitempty"`
+	Created            string                   `json:"created,omitempty"`
+	Modified           string                   `json:"modified,omitempty"`
+	Name               string                   `json:"name,omitempty"`
+	Description        string                   `json:"description,omitempty"`
+	Labels             []string                 `json:"labels,omitempty"`
+	IdentityClass      string                   `json:"identity_class,omitempty"`
+	Value              string                   `json:"value,omitempty"`
+	FirstObserved      string                   `json:"first_observed,omitempty"`
+	LastObserved       string                   `json:"last_observed,omitempty"`
+	NumberObserved     int                      `json:"number_observed,omitempty"`
+	IndicatorTypes     []string                 `json:"indicator_types,omitempty"`
+	Pattern            string                   `json:"pattern,omitempty"`
+	PatternType        string                   `json:"pattern_type,omitempty"`
+	ValidFrom          string                   `json:"valid_from,omitempty"`
+	Content            string                   `json:"content,omitempty"`
+	Authors            []string                 `json:"authors,omitempty"`
+	RelationshipType   string                   `json:"relationship_type,omitempty"`
+	SourceRef          string                   `json:"source_ref,omitempty"`
+	TargetRef          string                   `json:"target_ref,omitempty"`
+	ObjectRefs         []string                 `json:"object_refs,omitempty"`
+	ExternalReferences []*STIXExternalReference `json:"external_references,omitempty"`
+}
+
+// STIXExternalReference is a STIX 2.1 external reference.
+type STIXExternalReference struct {
+	SourceName string `json:"source_name"`
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// NewSTIXBundle maps the enriched offense into a STIX 2.1 bundle:
+//
+//   - the offense is an incident, labelled with its categories
+//   - the source and local destination IPs are ipv4-addr or ipv6-addr objects
+//   - the IPs are observed in an observed-data object, with the event count
+//   - every source IP is an indicator, described with the rule names
+//   - every note is a note object
+//
+// The IDs are deterministic, so that a new export of the same offense updates
+// the objects of the previous one.
+func NewSTIXBundle(offense *EnrichedOffense, opts *STIXOptions) (*STIXBundle, error) {
+	if offense == nil || offense.Offense == nil {
+		return nil, fmt.Errorf("the offense is required")
+	}
+	if opts == nil {
+		opts = &STIXOptions{}
+	}
+
+	prefix := opts.Namespace + "|offense|" + strconv.Itoa(offense.ID)
+	created := stixTime(offense.StartTime)
+	modified := stixTime(offense.LastUpdatedTime)
+	if offense.LastUpdatedTime < offense.StartTime {
+		modified = created
+	}
+
+	objects := []*STIXObject{}
+
+	// Identity
+	identityRef := ""
+	if opts.IdentityName != "" {
+		identity := &STIXObject{
+			Type:          "identity",
+			SpecVersion:   "2.1",
+			ID:            stixID("identity", stixSDONamespace, opts.Namespace+"|identity|"+opts.IdentityName),
+			Created:       stixIdentityTime,
+			Modified:      stixIdentityTime,
+			Name:          opts.IdentityName,
+			IdentityClass: "organization",
+		}
+		identityRef = identity.ID
+		objects = append(objects, identity)
+	}
+
+	// Incident
+	incident := &STIXObject{
+		Type:         "incident",
+		SpecVersion:  "2.1",
+		ID:           stixID("incident", stixSDONamespace, prefix),
+		CreatedByRef: identityRef,
+		Created:      created,
+		Modified:     modified,
+		Name:         strings.TrimSuffix(fmt.Sprintf("QRadar offense %d: %s", offense.ID, strings.TrimSpace(offense.Description)), ": "),
+		Description:  offense.Description,
+		Labels:       offense.Categories,
+		ExternalReferences: []*STIXExternalReference{
+			{SourceName: "qradar", ExternalID: strconv.Itoa(offense.ID)},
+		},
+	}
+	if offense.OffenseTypeName != "" {
+		incident.Labels = append([]string{offense.OffenseTypeName}, incident.Labels...)
+	}
+	objects = append(objects, incident)
+
+	// Addresses
+	addresses := map[string]*STIXObject{}
+	addressRefs := []string{}
+	for _, ip := range append(append([]string{}, offense.SourceIPs...), offense.LocalDestinationIPs...) {
+		if _, ok := addresses[ip]; ok {
+			continue
+		}
+		address, err := stixAddress(ip)
+		if err != nil {
+			return nil, err
+		}
+		addresses[ip] = address
+		addressRefs = append(addressRefs, address.ID)
+		objects = append(objects, address)
+	}
+
+	relate := func(source *STIXObject) {
+		objects = append(objects, &STIXObject{
+			Type:             "relationship",
+			SpecVersion:      "2.1",
+			ID:               stixID("relationship", stixSDONamespace, prefix+"|related-to|"+source.ID),
+			CreatedByRef:     identityRef,
+			Created:          created,
+			Modified:         modified,
+			RelationshipType: "related-to",
+			SourceRef:        source.ID,
+			TargetRef:        incident.ID,
+		})
+	}
+
+	// Observed data
+	if len(addressRefs) > 0 {
+		count := offense.EventCount + offense.FlowCount
+		if count < 1 {
+			count = 1
+		}
+		observed := &STIXObject{
+			Type:           "observed-data",
+			SpecVersion:    "2.1",
+			ID:             stixID("observed-data", stixSDONamespace, prefix+"|observed-data"),
+			CreatedByRef:   identityRef,
+			Created:        created,
+			Modified:       modified,
+			FirstObserved:  created,
+			LastObserved:   modified,
+			NumberObserved: count,
+			ObjectRefs:     addressRefs,
+		}
+		objects = append(objects, observed)
+		relate(observed)
+	}
+
+	// Indicators
+	for _, ip := range offense.SourceIPs {
+		address := addresses[ip]
+		indicator := &STIXObject{
+			Type:           "indicator",
+			SpecVersion:    "2.1",
+			ID:             stixID("indicator", stixSDONamespace, prefix+"|indicator|"+address.ID),
+			CreatedByRef:   identityRef,
+			Created:        created,
+			Modified:       modified,
+			Name:           fmt.Sprintf("Source of QRadar offense %d", offense.ID),
+			IndicatorTypes: []string{"anomalous-activity"},
+			Pattern:        fmt.Sprintf("[%s:value = '%s']", address.Type, address.Value),
+			PatternType:    "stix",
+			ValidFrom:      created,
+		}
+		if len(offense.RuleNames) > 0 {
+			indicator.Description = "Rules: " + strings.Join(offense.RuleNames, ", ")
+		}
+		objects = append(objects, indicator)
+		relate(indicator)
+	}
+
+	// Notes
+	for _, note := range offense.Notes {
+		object := &STIXObject{
+			Type:         "note",
+			SpecVersion:  "2.1",
+			ID:           stixID("note", stixSDONamespace, prefix+"|note|"+strconv.Itoa(note.ID)),
+			CreatedByRef: identityRef,
+			Created:      stixTime(note.CreateTime),
+			Modified:     stixTime(note.CreateTime),
+			Content:      note.NoteText,
+			ObjectRefs:   []string{incident.ID},
+		}
+		if note.Username != "" {
+			object.Authors = []string{note.Username}
+		}
+		objects = append(objects, object)
+	}
+
+	// Sort the objects after the identity and the incident, for stable outputs
+	rest := objects[1:]
+	if identityRef != "" {
+		rest = objects[2:]
+	}
+	sort.SliceStable(rest, func(i, j int) bool {
+		if rest[i].Type != rest[j].Type {
+			return rest[i].Type < rest[j].Type
+		}
+		return rest[i].ID < rest[j].ID
+	})
+
+	return &STIXBundle{
+		Type:    "bundle",
+		ID:      stixID("bundle", stixSDONamespace, prefix+"|bundle|"+modified),
+		Objects: objects,
+	}, nil
+}
+
+// WriteSTIXBundle writes the STIX 2.1 bundle of the enriched offense as JSON.
+func WriteSTIXBundle(w io.Writer, offense *EnrichedOffense, opts *STIXOptions) error {
+	bundle, err := NewSTIXBundle(offense, opts)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	if opts != nil && opts.Indent {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(bundle); err != nil {
+		return fmt.Errorf("error while writing the bundle: %s", err)
+	}
+
+	return nil
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+func stixAddress(value string) (*STIXObject, error) {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP: %s", value)
+	}
+
+	kind := "ipv6-addr"
+	if ip.To4() != nil {
+		kind = "ipv4-addr"
+	}
+
+	// The ID is computed from the value, as defined by the specification
+	b, _ := json.Marshal(map[string]string{"value": ip.String()})
+
+	return &STIXObject{
+		Type:        kind,
+		SpecVersion: "2.1",
+		ID:          stixID(kind, stixSCONamespace, string(b)),
+		Value:       ip.String(),
+	}, nil
+}
+
+func stixID(kind string, namespace [16]byte, name string) string {
+	return kind + "--" + uuidV5(namespace, name)
+}
+
+func stixTime(ms int) string {
+	return time.Unix(0, int64(ms)*int64(time.Millisecond)).UTC().Format(stixTimeFormat)
+}
+
+// uuidV5 returns the name-based UUID of the name in the namespace, using SHA-1.
+func uuidV5(namespace [16]byte, name string) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	sum := h.Sum(nil)
+
+	var u [16]byte
+	copy(u[:], sum[:16])
+	u[6] = (u[6] & 0x0f) | 0x50
+	u[8] = (u[8] & 0x3f) | 0x80
+
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+func mustParseUUID(s string) [16]byte {
+	var u [16]byte
+	b, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil || len(b) != 16 {
+		panic("invalid UUID: " + s)
+	}
+	copy(u[:], b)
+
+	return u
+}
@@ -0,0 +1,86 @@
+package goqradar
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestUUIDV5(t *testing.T) {
+	dns := mustParseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	if id := uuidV5(dns, "python.org"); id != "886313e1-3b8a-5372-9b90-0c9aee199e5d" {
+		t.Fatalf("unexpected UUID: %s", id)
+	}
+}
+
+func TestWriteSTIXBundle(t *testing.T) {
+	offense := &EnrichedOffense{
+		Offense: &Offense{
+			ID:              42,
+			Description:     "Brute force\n",
+			Categories:      []string{"Authentication"},
+			StartTime:       1600000000000,
+			LastUpdatedTime: 1600003600000,
+			EventCount:      12,
+		},
+		SourceIPs:           []string{"10.0.0.1"},
+		LocalDestinationIPs: []string{"10.0.0.2", "10.0.0.1"},
+		RuleNames:           []string{"Login failures"},
+		Notes:               []*Note{{ID: 1, CreateTime: 1600000100000, NoteText: "checked", Username: "admin"}},
+	}
+	opts := &STIXOptions{Namespace: "console.example.com", IdentityName: "SOC"}
+
+	var first, second bytes.Buffer
+	if err := WriteSTIXBundle(&first, offense, opts); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if err := WriteSTIXBundle(&second, offense, opts); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if first.String() != second.String() {
+		t.Fatal("the bundles should be identical")
+	}
+
+	var bundle STIXBundle
+	if err := json.Unmarshal(first.Bytes(), &bundle); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	types := map[string]int{}
+	byID := map[string]*STIXObject{}
+	for _, object := range bundle.Objects {
+		types[object.Type]++
+		byID[object.ID] = object
+	}
+	expected := map[string]int{"identity": 1, "incident": 1, "ipv4-addr": 2, "observed-data": 1, "indicator": 1, "note": 1, "relationship": 2}
+	for kind, count := range expected {
+		if types[kind] != count {
+			t.Fatalf("unexpected objects: %v", types)
+		}
+	}
+
+	// The ID of the observable is defined by the specification
+	address := byID["ipv4-addr--7dd44d27-f473-5ba9-b12b-0d3a61bbed2e"]
+	if address == nil || address.Value != "10.0.0.1" {
+		t.Fatalf("unexpected address: %+v", address)
+	}
+
+	// The identity does not depend on the offense
+	identity := bundle.Objects[0]
+	if identity.Type != "identity" || identity.Created != stixIdentityTime || identity.Modified != stixIdentityTime {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+	other, err := NewSTIXBundle(&EnrichedOffense{Offense: &Offense{ID: 43, StartTime: 1700000000000}}, opts)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if !reflect.DeepEqual(other.Objects[0], identity) {
+		t.Fatalf("the identities should be identical: %+v", other.Objects[0])
+	}
+
+	incident := bundle.Objects[1]
+	if incident.Type != "incident" || incident.Name != "QRadar offense 42: Brute force" || incident.Created != "2020-09-13T12:26:40.000Z" {
+		t.Fatalf("unexpected incident: %+v", incident)
+	}
+}
@@ -0,0 +1,102 @@
+package goqradar
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Span attribute keys set by the client on every request span.
+const (
+	AttributeEndpoint   = "qradar.endpoint"
+	AttributeMethod     = "http.method"
+	AttributeStatusCode = "http.status_code"
+	AttributeRange      = "qradar.range"
+)
+
+//------------------------------------------------------------------------------
+// Interfaces
+//------------------------------------------------------------------------------
+
+// Tracer starts spans around the API calls. It mirrors the shape of the
+// OpenTelemetry tracer so that an adapter is only a few lines long.
+type Tracer interface {
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+// Span is a single traced API call.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// HeaderInjector can optionally be implemented by a Tracer to propagate the
+// span context to QRadar through the request headers.
+type HeaderInjector interface {
+	Inject(ctx context.Context, header http.Header)
+}
+
+//------------------------------------------------------------------------------
+// No-op implementation
+//------------------------------------------------------------------------------
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+var searchIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// tracer returns the configured tracer or a no-op one.
+func (c *Client) tracer() Tracer {
+	if c.Tracer == nil {
+		return noopTracer{}
+	}
+
+	return c.Tracer
+}
+
+// endpointTemplate replaces the identifiers of an endpoint by a placeholder,
+// so that "/siem/offenses/42/notes" becomes "/siem/offenses/{id}/notes".
+func endpointTemplate(endpoint string) string {
+	// Remove the query string, if any
+	if i := strings.Index(endpoint, "?"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+
+	segments := strings.Split(endpoint, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+
+		if isNumeric(segment) || searchIDPattern.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return s != ""
+}
@@ -0,0 +1,73 @@
+package goqradar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recordingTracer struct {
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	span := &recordingSpan{name: spanName, attributes: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+type recordingSpan struct {
+	name       string
+	attributes map[string]interface{}
+	ended      bool
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *recordingSpan) RecordError(err error)                      {}
+func (s *recordingSpan) End()                                       { s.ended = true }
+
+func TestEndpointTemplate(t *testing.T) {
+	if got := endpointTemplate("/siem/offenses/42/notes"); got != "/siem/offenses/{id}/notes" {
+		t.Fatalf("unexpected template: %s", got)
+	}
+
+	if got := endpointTemplate("/ariel/searches/6a1b6d8e-0e3c-4d3c-9b1e-2f1d2c3b4a59/results"); got != "/ariel/searches/{id}/results" {
+		t.Fatalf("unexpected template: %s", got)
+	}
+}
+
+func TestDoTracing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "items 0-0/0")
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	tracer := &recordingTracer{}
+	client := NewClient(nil, server.URL, "token")
+	client.Tracer = tracer
+
+	_, err := client.SIEM.ListOffenses(context.Background(), "", "", "", 0, 49)
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("should have one span, got %d", len(tracer.spans))
+	}
+
+	span := tracer.spans[0]
+	if !span.ended {
+		t.Fatal("span should be ended")
+	}
+	if span.attributes[AttributeEndpoint] != "/siem/offenses" {
+		t.Fatalf("unexpected endpoint attribute: %v", span.attributes[AttributeEndpoint])
+	}
+	if span.attributes[AttributeStatusCode] != 200 {
+		t.Fatalf("unexpected status attribute: %v", span.attributes[AttributeStatusCode])
+	}
+	if span.attributes[AttributeRange] != "items=0-49" {
+		t.Fatalf("unexpected range attribute: %v", span.attributes[AttributeRange])
+	}
+}
@@ -0,0 +1,406 @@
+package goqradar
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultWebhookMaxAttempts   = 5
+	defaultWebhookRetryInterval = time.Minute
+	defaultWebhookTimeout       = 30 * time.Second
+	deliveryLogFile             = "deliveries.ndjson"
+)
+
+// Headers of the webhook requests.
+const (
+	WebhookSignatureHeader = "X-QRadar-Signature"
+	WebhookDeliveryHeader  = "X-QRadar-Delivery"
+)
+
+// Statuses of the deliveries.
+const (
+	DeliveryPending   = "PENDING"
+	DeliveryDelivered = "DELIVERED"
+	DeliveryFailed    = "FAILED"
+)
+
+//------------------------------------------------------------------------------
+// Structures
+//------------------------------------------------------------------------------
+
+// Webhook is a destination of the forwarder.
+type Webhook struct {
+	URL string
+
+	// Secret signs the body with HMAC-SHA256, in the X-QRadar-Signature header. It is optional.
+	Secret string
+
+	// Template renders the payload from the OffenseEvent. Default is the built-in JSON payload.
+	Template *template.Template
+
+	// ContentType is the content type of the payload. Default is application/json.
+	ContentType string
+
+	// Headers are additional headers, such as an authorization.
+	Headers map[string]string
+}
+
+// WebhookOptions are the options of a webhook forwarder.
+type WebhookOptions struct {
+	Webhooks []*Webhook
+
+	// QueueDir is the directory of the failed deliveries, which are retried,
+	// and of the delivery log. Without it, the failed deliveries are dropped.
+	QueueDir string
+
+	// MaxAttempts is the number of attempts of a delivery. Default is 5.
+	MaxAttempts int
+
+	// RetryInterval is the time between two retries of the queue. Default is 1 minute.
+	RetryInterval time.Duration
+
+	// HTTPClient is the client of the webhooks. Default is a client with a 30 seconds timeout.
+	HTTPClient *http.Client
+
+	// OnDelivery is called after every attempt.
+	OnDelivery func(*Delivery)
+}
+
+// WebhookPayload is the built-in JSON payload.
+type WebhookPayload struct {
+	Type     string        `json:"type"`
+	Offense  *Offense      `json:"offense"`
+	Previous *OffenseState `json:"previous,omitempty"`
+}
+
+// Delivery is the delivery of a payload to a webhook.
+type Delivery struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	OffenseID   int       `json:"offense_id"`
+	EventType   string    `json:"event_type"`
+	Payload     string    `json:"payload"`
+	ContentType string    `json:"content_type"`
+	Attempts    int       `json:"attempts"`
+	Status      string    `json:"status"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookForwarder forwards the offense events to webhooks.
+type WebhookForwarder struct {
+	opts     WebhookOptions
+	webhooks map[string]*Webhook
+
+	mu sync.Mutex
+}
+
+//------------------------------------------------------------------------------
+// Factory
+//------------------------------------------------------------------------------
+
+// NewWebhookForwarder returns a new webhook forwarder.
+func NewWebhookForwarder(opts *WebhookOptions) (*WebhookForwarder, error) {
+	if opts == nil || len(opts.Webhooks) == 0 {
+		return nil, fmt.Errorf("at least one webhook is required")
+	}
+
+	f := &WebhookForwarder{
+		opts:     *opts,
+		webhooks: map[string]*Webhook{},
+	}
+	if f.opts.MaxAttempts <= 0 {
+		f.opts.MaxAttempts = defaultWebhookMaxAttempts
+	}
+	if f.opts.RetryInterval <= 0 {
+		f.opts.RetryInterval = defaultWebhookRetryInterval
+	}
+	if f.opts.HTTPClient == nil {
+		f.opts.HTTPClient = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+	for _, webhook := range f.opts.Webhooks {
+		if webhook.URL == "" {
+			return nil, fmt.Errorf("the URL of the webhook is required")
+		}
+		f.webhooks[webhook.URL] = webhook
+	}
+	if f.opts.QueueDir != "" {
+		if err := os.MkdirAll(f.opts.QueueDir, 0755); err != nil {
+			return nil, fmt.Errorf("error while creating the queue: %s", err)
+		}
+	}
+
+	return f, nil
+}
+
+//------------------------------------------------------------------------------
+// Functions
+//------------------------------------------------------------------------------
+
+// Run forwards the events of the channel, such as the events of an
+// OffenseWatcher, and retries the queue, until the channel is closed or the
+// context is canceled.
+func (f *WebhookForwarder) Run(ctx context.Context, events <-chan *OffenseEvent) error {
+	ticker := time.NewTicker(f.opts.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := f.Forward(ctx, event); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := f.RetryQueue(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Forward renders the event and delivers it to every webhook. A failed
+// delivery is queued, and a payload which cannot be rendered is a failed
+// delivery. The error is only returned if the queue cannot be written.
+func (f *WebhookForwarder) Forward(ctx context.Context, event *OffenseEvent) error {
+	for _, webhook := range f.opts.Webhooks {
+		contentType := webhook.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+
+		delivery := &Delivery{
+			ID:          deliveryID(webhook.URL, event),
+			URL:         webhook.URL,
+			OffenseID:   event.Offense.ID,
+			EventType:   event.Type,
+			ContentType: contentType,
+		}
+
+		payload, err := renderWebhookPayload(webhook, event)
+		if err != nil {
+			if err := f.settle(delivery, fmt.Errorf("error while rendering the payload: %s", err), false); err != nil {
+				return err
+			}
+			continue
+		}
+		delivery.Payload = string(payload)
+
+		if err := f.attempt(ctx, webhook, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RetryQueue retries the queued deliveries. A delivery to a webhook which is
+// no longer configured fails, and a file of the queue which cannot be
+// unmarshalled is renamed with the .invalid extension, so that it is not retried.
+func (f *WebhookForwarder) RetryQueue(ctx context.Context) error {
+	if f.opts.QueueDir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(f.opts.QueueDir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("error while listing the queue: %s", err)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// The file is retried at the next interval
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var delivery *Delivery
+		if err := json.Unmarshal(b, &delivery); err != nil || delivery == nil {
+			if err := os.Rename(path, path+".invalid"); err != nil {
+				return fmt.Errorf("error while setting aside the delivery %s: %s", path, err)
+			}
+			continue
+		}
+
+		// The webhook may have been removed from the configuration
+		webhook, ok := f.webhooks[delivery.URL]
+		if !ok {
+			delivery.StatusCode = 0
+			if err := f.settle(delivery, fmt.Errorf("the webhook is no longer configured"), false); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := f.attempt(ctx, webhook, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SignWebhookPayload returns the value of the X-QRadar-Signature header of the body.
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature returns true if the signature header matches the body.
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, body)), []byte(signature))
+}
+
+//------------------------------------------------------------------------------
+// Helpers
+//------------------------------------------------------------------------------
+
+// attempt delivers the payload and settles the delivery.
+func (f *WebhookForwarder) attempt(ctx context.Context, webhook *Webhook, delivery *Delivery) error {
+	delivery.Attempts++
+
+	statusCode, err := f.post(ctx, webhook, delivery)
+	delivery.StatusCode = statusCode
+
+	return f.settle(delivery, err, true)
+}
+
+// settle sets the status of the delivery from the error, then queues or
+// dequeues the delivery and records its status. A delivery which cannot be
+// retried fails at the first error.
+func (f *WebhookForwarder) settle(delivery *Delivery, err error, retry bool) error {
+	delivery.Error = ""
+	delivery.UpdatedAt = time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = DeliveryDelivered
+	case retry && f.opts.QueueDir != "" && delivery.Attempts < f.opts.MaxAttempts:
+		delivery.Status, delivery.Error = DeliveryPending, err.Error()
+	default:
+		delivery.Status, delivery.Error = DeliveryFailed, err.Error()
+	}
+
+	if f.opts.QueueDir != "" {
+		path := filepath.Join(f.opts.QueueDir, delivery.ID+".json")
+		if delivery.Status == DeliveryPending {
+			if err := writeJSONFile(path, delivery); err != nil {
+				return fmt.Errorf("error while queuing the delivery: %s", err)
+			}
+		} else if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error while dequeuing the delivery: %s", err)
+		}
+
+		if err := f.record(delivery); err != nil {
+			return err
+		}
+	}
+
+	if f.opts.OnDelivery != nil {
+		f.opts.OnDelivery(delivery)
+	}
+
+	return nil
+}
+
+func (f *WebhookForwarder) post(ctx context.Context, webhook *Webhook, delivery *Delivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("error while creating the request: %s", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", delivery.ContentType)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	for key, value := range webhook.Headers {
+		req.Header.Set(key, value)
+	}
+	if webhook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, body))
+	}
+
+	resp, err := f.opts.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error while calling the webhook: %s", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("error with the status code: %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// record appends the status of the delivery to the delivery log.
+func (f *WebhookForwarder) record(delivery *Delivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(filepath.Join(f.opts.QueueDir, deliveryLogFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error while opening the delivery log: %s", err)
+	}
+	defer file.Close()
+
+	// The payload is in the queue, not in the log
+	record := *delivery
+	record.Payload = ""
+	if err := json.NewEncoder(file).Encode(&record); err != nil {
+		return fmt.Errorf("error while writing the delivery log: %s", err)
+	}
+
+	return nil
+}
+
+func renderWebhookPayload(webhook *Webhook, event *OffenseEvent) ([]byte, error) {
+	if webhook.Template == nil {
+		return json.Marshal(&WebhookPayload{Type: event.Type, Offense: event.Offense, Previous: event.Previous})
+	}
+
+	var b bytes.Buffer
+	if err := webhook.Template.Execute(&b, event); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// deliveryID returns a stable ID of the delivery of the event to the URL, so
+// that the receivers can ignore the duplicates.
+func deliveryID(url string, event *OffenseEvent) string {
+	key := strings.Join([]string{url, strconv.Itoa(event.Offense.ID), event.Type, strconv.Itoa(event.Offense.LastUpdatedTime)}, "|")
+	sum := sha1.Sum([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
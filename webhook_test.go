@@ -0,0 +1,166 @@
+package goqradar
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestWebhookForwarder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goqradar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	failing := true
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !VerifyWebhookSignature("secret", body, r.Header.Get(WebhookSignatureHeader)) {
+			t.Errorf("invalid signature")
+		}
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	forwarder, err := NewWebhookForwarder(&WebhookOptions{
+		Webhooks: []*Webhook{{
+			URL:      server.URL,
+			Secret:   "secret",
+			Template: template.Must(template.New("chat").Parse(`{"text":"Offense {{.Offense.ID}} {{.Type}}"}`)),
+		}},
+		QueueDir: dir,
+	})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	event := &OffenseEvent{Type: OffenseCreated, Offense: &Offense{ID: 42}}
+	if err := forwarder.Forward(context.Background(), event); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	// The delivery is queued
+	queued, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(queued) != 1 {
+		t.Fatalf("should have 1 queued delivery, got %d", len(queued))
+	}
+
+	failing = false
+	if err := forwarder.RetryQueue(context.Background()); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if len(bodies) != 1 || bodies[0] != `{"text":"Offense 42 CREATED"}` {
+		t.Fatalf("unexpected bodies: %v", bodies)
+	}
+	queued, _ = filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(queued) != 0 {
+		t.Fatalf("should have no queued delivery, got %d", len(queued))
+	}
+
+	// The statuses are recorded
+	log, err := ioutil.ReadFile(filepath.Join(dir, deliveryLogFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"status":"PENDING"`) || !strings.Contains(lines[1], `"status":"DELIVERED"`) {
+		t.Fatalf("unexpected log: %s", log)
+	}
+}
+
+func TestWebhookForwarderFailures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goqradar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	posts := 0
+	var payload WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid payload: %s", err)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	deliveries := map[string]*Delivery{}
+	forwarder, err := NewWebhookForwarder(&WebhookOptions{
+		Webhooks: []*Webhook{
+			{URL: "http://broken.example.com", Template: template.Must(template.New("broken").Parse(`{{.Offense.Missing}}`))},
+			{URL: server.URL},
+		},
+		QueueDir:    dir,
+		MaxAttempts: 2,
+		OnDelivery: func(delivery *Delivery) {
+			recorded := *delivery
+			deliveries[delivery.URL] = &recorded
+		},
+	})
+	if err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+
+	// The template error does not stop the other webhooks
+	event := &OffenseEvent{Type: OffenseClosed, Offense: &Offense{ID: 42, Status: OffenseStatusClosed}}
+	if err := forwarder.Forward(context.Background(), event); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if broken := deliveries["http://broken.example.com"]; broken == nil || broken.Status != DeliveryFailed || broken.Attempts != 0 {
+		t.Fatalf("unexpected delivery: %+v", broken)
+	}
+	if deliveries[server.URL].Status != DeliveryPending {
+		t.Fatalf("unexpected delivery: %+v", deliveries[server.URL])
+	}
+
+	// The built-in JSON payload
+	if payload.Type != OffenseClosed || payload.Offense == nil || payload.Offense.ID != 42 || payload.Offense.Status != OffenseStatusClosed {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+
+	// A delivery to a removed webhook, and a file which cannot be unmarshalled
+	removed := &Delivery{ID: "removed", URL: "http://removed.example.com", Payload: "{}", Attempts: 1, Status: DeliveryPending}
+	if err := writeJSONFile(filepath.Join(dir, "removed.json"), removed); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "invalid.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := forwarder.RetryQueue(context.Background()); err != nil {
+		t.Fatalf("should not error but error is: %s", err)
+	}
+	if delivery := deliveries[server.URL]; delivery.Status != DeliveryFailed || delivery.Attempts != 2 || delivery.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected delivery: %+v", delivery)
+	}
+	if delivery := deliveries["http://removed.example.com"]; delivery == nil || delivery.Status != DeliveryFailed || delivery.Attempts != 1 {
+		t.Fatalf("unexpected delivery: %+v", delivery)
+	}
+	if posts != 2 {
+		t.Fatalf("should have 2 posts, got %d", posts)
+	}
+
+	// The queue is empty, the invalid file is set aside
+	queued, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(queued) != 0 {
+		t.Fatalf("should have no queued delivery, got %v", queued)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "invalid.json.invalid")); err != nil {
+		t.Fatalf("the invalid file should be set aside: %s", err)
+	}
+}